@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"../../edgo"
+)
+
+func runDirs(args []string) error {
+	fs := flag.NewFlagSet("dirs", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: edgo dirs\n\n"+
+			"Lists the journal directories found in the Windows profile and in\n"+
+			"Steam (Proton), Lutris, Heroic and Wine prefixes, the one with the\n"+
+			"most recent journal first. That one is used by default.\n")
+	}
+	fs.Parse(args)
+
+	dirs := edgo.FindJournalDirs()
+	if len(dirs) == 0 {
+		return fmt.Errorf("dirs: no journal directory found")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, d := range dirs {
+		latest := "-"
+		if d.LatestJournal != "" {
+			latest = d.Latest.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", d.Source, latest, d.Path)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"../../edgo"
+)
+
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Write the journal and status files to `directory`.")
+	realtime := fs.Bool("realtime", false, "Write events as they happen, for a watcher to follow.")
+	speed := fs.Float64("speed", 1, "Speed up -realtime by `factor`.")
+	replace := fs.Bool("replace", false, "Replace a journal of the same name, such as one generated before.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: edgo generate [flags] [scenario file]\n\n"+
+			"Plays a scenario, read from standard input if no file is given,\n"+
+			"writing a journal as the game would. See edgo.Scenario.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var r io.Reader = os.Stdin
+	if fs.NArg() > 0 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	scenario, err := edgo.ParseScenario(r)
+	if err != nil {
+		return err
+	}
+
+	g := &edgo.Generator{Dir: *dir, Realtime: *realtime, Speed: *speed, Replace: *replace}
+	return g.Run(scenario)
+}
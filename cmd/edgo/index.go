@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"../../edgo"
+	"../../edgo/store"
+)
+
+func runIndex(args []string) error {
+	fs := flag.NewFlagSet("index", flag.ExitOnError)
+	db := fs.String("db", defaultStoreDir(), "Store `directory`.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: edgo index [flags] [journal, .gz, .zip or directory...]\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{defaultJournalDir()}
+	}
+
+	st, err := store.Open(*db)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	gs := edgo.NewGameState()
+	lines := make(map[string]int)
+	added := 0
+	lastFlush := time.Now()
+	bulk := &edgo.BulkReader{
+		Files: paths,
+		Progress: func(p edgo.BulkProgress) {
+			if lines[p.LastFile] > st.Indexed(p.LastFile) {
+				st.SetIndexed(p.LastFile, lines[p.LastFile])
+			}
+			// Flush now and then, so that an interrupted index resumes
+			// where it left off.
+			if time.Since(lastFlush) > 5*time.Second {
+				if err := st.Flush(); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+				lastFlush = time.Now()
+			}
+			fmt.Fprintf(os.Stderr, "\r%d/%d files, %d events", p.Files, p.TotalFiles, p.Events)
+		},
+	}
+	err = bulk.Run(func(e *edgo.BulkEvent) error {
+		// Each session starts with its own LoadGame and Location, so the
+		// system, station and ship context is reset with each session.
+		if edgo.GetEventName(e.Event) == "Fileheader" {
+			if part, _ := e.Event.Int64("part"); part <= 1 {
+				gs = edgo.NewGameState()
+			}
+		}
+		gs.Update(e.Event)
+		lines[e.File] = e.Line
+		if e.Line <= st.Indexed(e.File) {
+			return nil
+		}
+
+		state := gs.Snapshot()
+		added++
+		return st.Add(&store.Record{
+			Time:    edgo.GetEventTimestamp(e.Event),
+			Event:   edgo.GetEventName(e.Event),
+			System:  state.StarSystem,
+			Station: state.Station,
+			Ship:    state.Ship,
+			File:    e.File,
+			Line:    e.Line,
+			Data:    json.RawMessage(e.Raw),
+		})
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("indexed %d new events, %d events total\n", added, st.Len())
+	return st.Close()
+}
@@ -0,0 +1,67 @@
+// Command edgo provides tools for working with Elite Dangerous journals.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"../../edgo"
+)
+
+type command struct {
+	run   func(args []string) error
+	usage string
+}
+
+var commands = map[string]command{
+	"dirs":     {runDirs, "list the journal directories found"},
+	"generate": {runGenerate, "write a journal from a scripted scenario"},
+	"index":    {runIndex, "index journals into a searchable store"},
+	"query":    {runQuery, "query the journal store"},
+	"relay":    {runRelay, "stream journals to another machine over TCP"},
+	"schema":   {runSchema, "infer the fields of journal events"},
+	"validate": {runValidate, "check status files against the edgo structs"},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", filepath.Base(os.Args[0]))
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].usage)
+	}
+	os.Exit(2)
+}
+
+// defaultJournalDir returns the detected journal directory; see
+// edgo.DefaultJournalDir.
+func defaultJournalDir() string {
+	return edgo.DefaultJournalDir()
+}
+
+// defaultStoreDir returns the default location of the journal store.
+func defaultStoreDir() string {
+	if cachedir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(cachedir, "edgo", "index")
+	}
+	return "edgo-index"
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
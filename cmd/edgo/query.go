@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"../../edgo/store"
+)
+
+func runQuery(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	db := fs.String("db", defaultStoreDir(), "Store `directory`.")
+	event := fs.String("event", "", "Only `name`d events, e.g. FSDJump.")
+	system := fs.String("system", "", "Only events in star `system`.")
+	station := fs.String("station", "", "Only events at `station`.")
+	ship := fs.String("ship", "", "Only events flying ship `type`.")
+	since := fs.String("since", "", "Only events at or after `time`, e.g. 2025 or 2025-03-01.")
+	until := fs.String("until", "", "Only events up to and including `time`.")
+	limit := fs.Int("limit", 0, "Return at most `n` events.")
+	format := fs.String("format", "table", "Output `format`: table, json or csv.")
+	fs.Parse(args)
+
+	q := store.Query{
+		Event:   *event,
+		System:  *system,
+		Station: *station,
+		Ship:    *ship,
+		Limit:   *limit,
+	}
+	var err error
+	if q.Since, err = queryTime(*since, false); err != nil {
+		return err
+	}
+	if q.Until, err = queryTime(*until, true); err != nil {
+		return err
+	}
+
+	st, err := store.Open(*db)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	records, err := st.Query(q)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tEVENT\tSYSTEM\tSTATION\tSHIP")
+		for _, r := range records {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Time, r.Event, r.System, r.Station, r.Ship)
+		}
+		return w.Flush()
+
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"time", "event", "system", "station", "ship", "file", "line"})
+		for _, r := range records {
+			w.Write([]string{r.Time, r.Event, r.System, r.Station, r.Ship, r.File, strconv.Itoa(r.Line)})
+		}
+		w.Flush()
+		return w.Error()
+	}
+	return errors.New("query: unknown format " + *format)
+}
+
+// queryTime converts a year, month, day or RFC 3339 time to a journal
+// timestamp. When end is set, the end of the given period is returned,
+// so that "-until 2025" includes all of 2025.
+func queryTime(s string, end bool) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	for _, f := range []struct {
+		layout string
+		years  int
+		months int
+		days   int
+	}{
+		{"2006", 1, 0, 0},
+		{"2006-01", 0, 1, 0},
+		{"2006-01-02", 0, 0, 1},
+		{time.RFC3339, 0, 0, 0},
+	} {
+		t, err := time.Parse(f.layout, s)
+		if err != nil {
+			continue
+		}
+		if end {
+			t = t.AddDate(f.years, f.months, f.days)
+			if f.layout == time.RFC3339 {
+				t = t.Add(time.Second)
+			}
+		}
+		return t.UTC().Format("2006-01-02T15:04:05Z"), nil
+	}
+	return "", errors.New("query: bad time " + s)
+}
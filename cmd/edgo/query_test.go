@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestQueryTime(t *testing.T) {
+	tests := []struct {
+		in    string
+		end   bool
+		want  string
+		error bool
+	}{
+		{"", false, "", false},
+		{"", true, "", false},
+		{"2025", false, "2025-01-01T00:00:00Z", false},
+		{"2025", true, "2026-01-01T00:00:00Z", false},
+		{"2025-03", false, "2025-03-01T00:00:00Z", false},
+		{"2025-12", true, "2026-01-01T00:00:00Z", false},
+		{"2025-03-01", false, "2025-03-01T00:00:00Z", false},
+		{"2024-02-28", true, "2024-02-29T00:00:00Z", false},
+		{"2025-03-01T12:30:00Z", false, "2025-03-01T12:30:00Z", false},
+		{"2025-03-01T12:30:00Z", true, "2025-03-01T12:30:01Z", false},
+		{"2025-03-01T14:30:00+02:00", false, "2025-03-01T12:30:00Z", false},
+		{"2025-13", false, "", true},
+		{"yesterday", false, "", true},
+		{"2025-03-01 12:30", false, "", true},
+	}
+	for _, tt := range tests {
+		got, err := queryTime(tt.in, tt.end)
+		if (err != nil) != tt.error || got != tt.want {
+			t.Errorf("queryTime(%q, %v) = %q, %v, want %q", tt.in, tt.end, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"../../edgo"
+)
+
+func runRelay(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "serve":
+			return runRelayServe(args[1:])
+		case "connect":
+			return runRelayConnect(args[1:])
+		}
+	}
+	return fmt.Errorf("usage: edgo relay serve [flags] [journal directory]\n" +
+		"       edgo relay connect [flags] [address]")
+}
+
+// interruptContext returns a context that is cancelled on Ctrl-C or
+// SIGTERM.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(sigc)
+		select {
+		case <-sigc:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+func runRelayServe(args []string) error {
+	fs := flag.NewFlagSet("relay serve", flag.ExitOnError)
+	addr := fs.String("listen", edgo.DefaultRelayAddr, "Listen on `address`.")
+	buffer := fs.Int("buffer", 10000, "Keep the latest `n` events for clients that reconnect.")
+	source := fs.String("source", "", "Tag events with `name`, to tell several relays apart.")
+	token := fs.String("token", os.Getenv("EDGO_RELAY_TOKEN"), "Require clients to send `token`; defaults to $EDGO_RELAY_TOKEN.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: edgo relay serve [flags] [journal directory]\n\n"+
+			"Watches the journal directory and streams its events to relay\n"+
+			"clients over TCP. Only local clients can connect unless -listen\n"+
+			"is given another address, which should be used with -token.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	dir := fs.Arg(0)
+	if dir == "" {
+		dir = defaultJournalDir()
+	}
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "relay: serving %s on %s\n", dir, l.Addr())
+	if tcp, ok := l.Addr().(*net.TCPAddr); ok && !tcp.IP.IsLoopback() && *token == "" {
+		fmt.Fprintln(os.Stderr, "relay: warning: anyone who can reach this address can read the journal; set -token")
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	w := edgo.NewEliteWatcher(dir, nil)
+	w.Source = *source
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- w.Run(ctx)
+	}()
+
+	srv := edgo.NewRelayServer(*buffer)
+	srv.Token = *token
+	err = srv.Serve(ctx, l, w.Journals)
+	cancel()
+	if werr := <-watchErr; err == nil {
+		err = werr
+	}
+	return err
+}
+
+func runRelayConnect(args []string) error {
+	fs := flag.NewFlagSet("relay connect", flag.ExitOnError)
+	token := fs.String("token", os.Getenv("EDGO_RELAY_TOKEN"), "Send `token` to the server; defaults to $EDGO_RELAY_TOKEN.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: edgo relay connect [flags] [address]\n\n"+
+			"Connects to a relay server, %s by default, and prints its\n"+
+			"events as JSON lines.\n\n", edgo.DefaultRelayAddr)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	addr := edgo.DefaultRelayAddr
+	if fs.NArg() > 0 {
+		addr = fs.Arg(0)
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	c := edgo.NewRelayClient(addr)
+	c.Token = *token
+	go c.Run(ctx)
+
+	enc := json.NewEncoder(os.Stdout)
+	for e := range c.Journals {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"../../edgo"
+)
+
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	format := fs.String("format", "report", "Output `format`: report or jsonschema.")
+	diff := fs.Bool("diff", false, "Compare against the fields known to the edgo status structs.")
+	event := fs.String("event", "", "Only report events matching `pattern`.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: edgo schema [flags] [journal, .gz, .zip or directory...]\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{defaultJournalDir()}
+	}
+	var filter *edgo.Filter
+	if *event != "" {
+		var err error
+		if filter, err = edgo.CompileFilter([]string{*event}, nil); err != nil {
+			return err
+		}
+	}
+
+	schema := edgo.NewSchema()
+	add := func(content edgo.Json) {
+		if filter.Match(edgo.GetEventName(content), content) {
+			schema.Add(content)
+		}
+	}
+	bulk := &edgo.BulkReader{Files: paths}
+	err := bulk.Run(func(e *edgo.BulkEvent) error {
+		add(e.Event)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Status files live next to the journals.
+	for _, p := range paths {
+		if fi, err := os.Stat(p); err != nil || !fi.IsDir() {
+			continue
+		}
+		for _, sf := range edgo.StatusFiles() {
+			data, err := ioutil.ReadFile(filepath.Join(p, sf.Filename))
+			if err != nil {
+				continue
+			}
+			if content, err := edgo.ParseJournalLine(data); err == nil {
+				add(content)
+			}
+		}
+	}
+
+	switch *format {
+	case "report":
+		printSchemaReport(schema, *diff)
+		return nil
+	case "jsonschema":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(schema.JSONSchema())
+	}
+	return errors.New("schema: unknown format " + *format)
+}
+
+// printSchemaReport prints each event and its fields. With diff, fields
+// that the edgo structs do not capture are marked "+", and struct fields
+// that never appeared are marked "-".
+func printSchemaReport(schema *edgo.Schema, diff bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	for _, name := range schema.Names() {
+		e := schema.Events[name]
+		var known map[string]bool
+		if diff {
+			if fields := edgo.KnownFields(name); fields != nil {
+				known = make(map[string]bool)
+				for _, f := range fields {
+					known[f] = false
+				}
+			}
+		}
+
+		header := fmt.Sprintf("%s (%d events)", name, e.Count)
+		if diff && known == nil {
+			header += " untyped"
+		}
+		fmt.Fprintln(w, header)
+		for _, path := range e.Paths() {
+			f := e.Fields[path]
+			mark := " "
+			if known != nil {
+				if _, ok := known[path]; ok {
+					known[path] = true
+				} else {
+					mark = "+"
+				}
+			}
+			presence := "always"
+			if e.Optional(path) {
+				presence = "optional"
+			}
+			fmt.Fprintf(w, "%s %s\t%s\t%s\t%s\n", mark, path,
+				strings.Join(f.TypeNames(), "|"), presence, strings.Join(f.Examples, ", "))
+		}
+		for _, path := range edgo.KnownFields(name) {
+			if seen, ok := known[path]; ok && !seen {
+				fmt.Fprintf(w, "- %s\t\t\t\n", path)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
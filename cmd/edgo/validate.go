@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"../../edgo"
+)
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	format := fs.String("format", "text", "Output `format`: text or json.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: edgo validate [flags] [directory...]\n\n"+
+			"Checks the status files in each directory against the edgo structs,\n"+
+			"and exits with status 1 if any field is unknown or missing.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *format != "text" && *format != "json" {
+		return errors.New("validate: unknown format " + *format)
+	}
+
+	dirs := fs.Args()
+	if len(dirs) == 0 {
+		dirs = []string{defaultJournalDir()}
+	}
+
+	var warnings []edgo.ValidationWarning
+	failed := 0
+	for _, dir := range dirs {
+		for _, sf := range edgo.StatusFiles() {
+			filename := filepath.Join(dir, sf.Filename)
+			data, err := ioutil.ReadFile(filename)
+			if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+				// The game only writes some files in some places, and
+				// truncates files before rewriting them.
+				continue
+			}
+			if err == nil {
+				var w []edgo.ValidationWarning
+				_, w, err = edgo.ParseStatusContentsStrict(filename, data)
+				warnings = append(warnings, w...)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+				failed++
+			}
+		}
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		for _, w := range warnings {
+			if err := enc.Encode(w); err != nil {
+				return err
+			}
+		}
+	} else {
+		for _, w := range warnings {
+			fmt.Println(w)
+		}
+	}
+
+	if len(warnings) > 0 || failed > 0 {
+		return fmt.Errorf("validate: %d warnings, %d files failed to parse", len(warnings), failed)
+	}
+	return nil
+}
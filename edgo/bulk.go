@@ -0,0 +1,164 @@
+package edgo
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"runtime"
+	"sort"
+)
+
+// BulkEvent is a journal event read by BulkReader.
+type BulkEvent struct {
+	File   string // journal file name
+	Source JournalSource
+	Line   int // line number within File, starting at 1
+	Raw    []byte
+	Event  Json
+}
+
+// BulkProgress reports how far a BulkReader has got.
+type BulkProgress struct {
+	Files      int    // files delivered
+	TotalFiles int    // files to read, excluding skipped ones
+	Events     int    // events delivered
+	Bytes      int64  // bytes read from delivered files
+	LastFile   string // name of the last file delivered; use as Resume to continue
+}
+
+// BulkReader parses many journal files using a pool of workers, and
+// delivers their events in order: files are sorted by the time in their
+// name, and the events of each file by timestamp.
+type BulkReader struct {
+	Files    []string           // journals, archives or directories; see FindJournals
+	Workers  int                // parsing goroutines; defaults to runtime.NumCPU()
+	Resume   string             // skip files sorting at or before this one
+	Progress func(BulkProgress) // called after each file is delivered
+}
+
+type bulkResult struct {
+	events []*BulkEvent
+	bytes  int64
+	err    error
+}
+
+// Run calls fn for every event in order. It stops at the first error
+// from reading a file or from fn.
+func (b *BulkReader) Run(fn func(*BulkEvent) error) error {
+	files, err := b.sources()
+	if err != nil {
+		return err
+	}
+	workers := b.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// Files are parsed ahead of delivery, but only a bounded number, so
+	// that memory use does not grow with the number of files.
+	results := make([]chan bulkResult, len(files))
+	for i := range results {
+		results[i] = make(chan bulkResult, 1)
+	}
+	ahead := make(chan struct{}, 2*workers)
+	jobs := make(chan int)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(jobs)
+		for i := range files {
+			select {
+			case ahead <- struct{}{}:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				results[i] <- parseBulkFile(files[i])
+			}
+		}()
+	}
+
+	progress := BulkProgress{TotalFiles: len(files)}
+	for i, file := range files {
+		r := <-results[i]
+		<-ahead
+		if r.err != nil {
+			return r.err
+		}
+		for _, e := range r.events {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		progress.Files++
+		progress.Events += len(r.events)
+		progress.Bytes += r.bytes
+		progress.LastFile = file.Name
+		if b.Progress != nil {
+			b.Progress(progress)
+		}
+	}
+	return nil
+}
+
+// sources returns the journals to read, in order.
+func (b *BulkReader) sources() ([]JournalSource, error) {
+	sources, err := FindJournals(b.Files...)
+	if err != nil || b.Resume == "" {
+		return sources, err
+	}
+	idx := sort.Search(len(sources), func(i int) bool {
+		return lessJournalName(b.Resume, sources[i].Name)
+	})
+	return sources[idx:], nil
+}
+
+func parseBulkFile(source JournalSource) bulkResult {
+	f, err := source.Open()
+	if err != nil {
+		return bulkResult{err: err}
+	}
+	defer f.Close()
+
+	var r bulkResult
+	reader := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		b, err := reader.ReadBytes('\n')
+		r.bytes += int64(len(b))
+		if err != nil && err != io.EOF {
+			r.err = err
+			return r
+		}
+		if b = bytes.TrimSpace(b); len(b) > 0 {
+			// Lines that fail to parse, such as a partial last line,
+			// are skipped.
+			if content, perr := ParseJournalLine(b); perr == nil {
+				r.events = append(r.events, &BulkEvent{
+					File:   source.Name,
+					Source: source,
+					Line:   line,
+					Raw:    b,
+					Event:  content,
+				})
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	sort.SliceStable(r.events, func(i, j int) bool {
+		return GetEventTimestamp(r.events[i].Event) < GetEventTimestamp(r.events[j].Event)
+	})
+	return r
+}
@@ -0,0 +1,115 @@
+package edgo
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeJournal(t testing.TB, dir, name string, lines ...string) {
+	t.Helper()
+	content := strings.Join(lines, "\n") + "\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBulkReaderMixedNames(t *testing.T) {
+	dir := t.TempDir()
+	// By name, the legacy 2021 journal would sort after the new format
+	// ones, and the 2020 journal between them.
+	files := []string{
+		"Journal.200101120000.01.log",
+		"Journal.201231235959.01.log",
+		"Journal.210113090000.01.log",
+		"Journal.210113090000.02.log",
+		"Journal.2021-05-20T081500.01.log",
+		"Journal.2025-01-01T120000.01.log",
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		writeJournal(t, dir, files[i], fmt.Sprintf(`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Test", "N":%d }`, i))
+	}
+
+	var got []string
+	bulk := &BulkReader{Files: []string{dir}, Workers: 2}
+	if err := bulk.Run(func(e *BulkEvent) error {
+		got = append(got, e.File)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != strings.Join(files, " ") {
+		t.Errorf("files read in order\n%v\nwant\n%v", got, files)
+	}
+
+	got = nil
+	bulk.Resume = files[2]
+	if err := bulk.Run(func(e *BulkEvent) error {
+		got = append(got, e.File)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != strings.Join(files[3:], " ") {
+		t.Errorf("resumed files\n%v\nwant\n%v", got, files[3:])
+	}
+}
+
+func TestJournalNameTime(t *testing.T) {
+	tests := []struct {
+		name string
+		time string
+		part int
+		ok   bool
+	}{
+		{"Journal.2025-01-01T120000.01.log", "2025-01-01 12:00:00", 1, true},
+		{"journal.2025-01-01t120000.02.log", "2025-01-01 12:00:00", 2, true},
+		{"Journal.170526124300.03.log", "2017-05-26 12:43:00", 3, true},
+		{"/x/Journal.170526124300.01.log", "2017-05-26 12:43:00", 1, true},
+		{"Status.json", "", 0, false},
+		{"Journal.notatime.01.log", "", 0, false},
+	}
+	for _, tt := range tests {
+		ts, part, ok := JournalNameTime(tt.name)
+		if ok != tt.ok || part != tt.part || (ok && ts.Format("2006-01-02 15:04:05") != tt.time) {
+			t.Errorf("JournalNameTime(%q) = %v, %d, %v", tt.name, ts, part, ok)
+		}
+	}
+}
+
+func BenchmarkBulkReader(b *testing.B) {
+	dir := b.TempDir()
+	lines := benchJournal(512 << 10)
+	var journal bytes.Buffer
+	for _, line := range lines {
+		journal.Write(line)
+		journal.WriteByte('\n')
+	}
+	const files = 16
+	for i := 0; i < files; i++ {
+		name := fmt.Sprintf("Journal.2025-01-%02dT120000.01.log", i+1)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), journal.Bytes(), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.SetBytes(files * int64(journal.Len()))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := 0
+		bulk := &BulkReader{Files: []string{dir}}
+		if err := bulk.Run(func(e *BulkEvent) error {
+			n++
+			return nil
+		}); err != nil {
+			b.Fatal(err)
+		}
+		if n != files*len(lines) {
+			b.Fatalf("read %d events, want %d", n, files*len(lines))
+		}
+	}
+}
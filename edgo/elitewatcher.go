@@ -1,19 +1,25 @@
 package edgo
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"log"
+	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"./watch"
 )
 
 var (
-	journalRE     = regexp.MustCompile(`^journal[.][0-9]+[.][0-9]+.log$`)
+	journalRE     = regexp.MustCompile(`^journal[.][0-9t-]+[.][0-9]+[.]log$`)
 	ErrEWShutdown = errors.New("elitewatcher: shutdown")
 )
 
@@ -23,19 +29,52 @@ var (
 //
 // Under the covers, EliteWatcher runs several goroutines. One
 // goroutine is dedicated to parsing the journal files and sending
-// those events.
+// those events. Run waits for all of them to exit before closing
+// Journals.
 type EliteWatcher struct {
 	DataDirectory string
 	Journals      chan interface{}
-	EventFilter   map[string]struct{}
-	watcher       *watch.Watcher
-	update        chan bool   // the existing journal has been updated
-	newjournal    chan string // a new journal file is sent,
-	statuswrite   chan string // the named status file has been updated
-	tail          *watch.Tail
-	shutdown      watch.Shutdown
+	EventFilter   *Filter
+	StatusFiles   map[string]struct{} // if set, only these status files are read
+	RawEvents     bool                // send *RawEvent instead of decoded Json
+	StatusDiffs   bool                // send *StatusDiff for changed status files
+	State         *GameState          // if set, updated with every event, even filtered ones
+	Source        string              // if set, events are sent as *SourcedEvent tagged with it
+
+	// IdleThresholds are the times without writes after which GameIdle
+	// events are sent; a GameResumed event follows the next write.
+	IdleThresholds []time.Duration
+
+	watcher     *watch.Watcher
+	update      chan bool   // the existing journal has been updated
+	newjournal  chan string // a new journal file is sent,
+	statuswrite chan string // the named status file has been updated
+	tail        *watch.Tail
+	statusCache map[string]*statusEntry // last status file sent, by file
+	lastStatus  *Status
+	session     *SessionEvent // the current session, nil once ended
+	watching    string        // the directory watched by handleLoop
+	watchInfo   os.FileInfo   // of watching, to tell when it is recreated
+	watchParent string        // the parent of watching, also watched
+	commander   string        // from the latest Commander or LoadGame event
+	shutdown    watch.Shutdown
+	done        <-chan struct{} // closed when Run is stopping
+
+	mu        sync.Mutex
+	running   bool          // guarded by mu
+	stop      chan struct{} // closed by Close
+	stopped   chan struct{} // closed when Run returns
+	closeOnce sync.Once
 }
 
+// statusEntry records the last content sent for a status file.
+type statusEntry struct {
+	sum    [sha256.Size]byte
+	fields Json
+}
+
+// NewEliteWatcher returns a watcher for dirname. shutdown is only used
+// by Main, and may be nil if the watcher is run with Run.
 func NewEliteWatcher(dirname string, shutdown watch.Shutdown) *EliteWatcher {
 	return &EliteWatcher{
 		DataDirectory: dirname,
@@ -44,16 +83,24 @@ func NewEliteWatcher(dirname string, shutdown watch.Shutdown) *EliteWatcher {
 		update:        make(chan bool, 1),
 		newjournal:    make(chan string, 1),
 		statuswrite:   make(chan string, 1),
+		statusCache:   make(map[string]*statusEntry),
 		shutdown:      shutdown,
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
 	}
 }
 
+// Close stops Run and waits for it to return.
 func (ew *EliteWatcher) Close() {
-	close(ew.Journals)
-	close(ew.update)
-	close(ew.newjournal)
-	close(ew.statuswrite)
-	ew.watcher.Close()
+	ew.closeOnce.Do(func() {
+		close(ew.stop)
+	})
+	ew.mu.Lock()
+	running := ew.running
+	ew.mu.Unlock()
+	if running {
+		<-ew.stopped
+	}
 }
 
 // setupInitialJournalFile scans the journal files in
@@ -73,7 +120,9 @@ func (ew *EliteWatcher) setupInitialJournalFile() error {
 		}
 	}
 	if len(journalFiles) > 0 {
-		sort.Strings(journalFiles)
+		sort.Slice(journalFiles, func(i, j int) bool {
+			return lessJournalName(journalFiles[i], journalFiles[j])
+		})
 		// name == basename
 		journal, err := filepath.Abs(filepath.Join(ew.DataDirectory, journalFiles[len(journalFiles)-1]))
 		if err != nil {
@@ -86,39 +135,45 @@ func (ew *EliteWatcher) setupInitialJournalFile() error {
 
 // fileTailer is the goroutine that is in charge of watching the
 // journal file, reading, and parsing those files.
-func (ew *EliteWatcher) fileTailer() {
+func (ew *EliteWatcher) fileTailer(ctx context.Context) error {
 
 	// Catch up with whatever status and journal file is set.
-	for _, fname := range []string{"cargo.json", "market.json", "modulesinfo.json", "navroute.json", "outfitting.json", "shipyard.json", "status.json"} {
-		if statusfile, err := filepath.Abs(filepath.Join(ew.DataDirectory, fname)); err == nil {
+	for _, sf := range StatusFiles() {
+		if statusfile, err := filepath.Abs(filepath.Join(ew.DataDirectory, sf.Filename)); err == nil {
 			ew.readAndParseStatusFile(statusfile)
 		}
 	}
 	ew.tailJournalFile()
 
+	idle := newIdleTracker(ew.IdleThresholds)
+	defer idle.stop()
+
 	// And loop forever receiving events.
 	var fname string
-	var ok bool
 	for {
 		select {
-		case fname, ok = <-ew.newjournal:
-			if !ok {
-				return
-			}
+		case fname = <-ew.newjournal:
+			ew.sendIdle(idle.activity())
 			ew.maybeSetJournalFile(fname)
 			ew.tailJournalFile()
 
-		case fname, ok = <-ew.statuswrite:
-			if !ok {
-				return
-			}
+		case fname = <-ew.statuswrite:
+			ew.sendIdle(idle.activity())
 			ew.readAndParseStatusFile(fname)
 
 		case <-ew.update:
+			ew.sendIdle(idle.activity())
 			ew.tailJournalFile()
 
-		case <-ew.shutdown.Dying():
-			return
+		case <-idle.C():
+			ew.sendIdle(idle.expired())
+
+		case <-ctx.Done():
+			if ew.tail != nil {
+				ew.tail.Close()
+				ew.tail = nil
+			}
+			return nil
 		}
 	}
 	panic("unreachable")
@@ -132,52 +187,301 @@ func (ew *EliteWatcher) tailJournalFile() {
 	ew.tail.ProcessLines(func(l string) error {
 		b := []byte(l)
 
-		// If ew.EventFilter is not empty, then check
-		// whether the existing event is one we're interested in
-		name := ""
-		if len(ew.EventFilter) > 0 {
-			name := GetEventNameByte(b)
-			if name != "" {
-				if _, ok := ew.EventFilter[name]; !ok {
+		// Scan the header first so that filtered events are dropped
+		// without decoding the line. Lines that cannot be scanned are
+		// decoded and filtered below.
+		var raw *RawEvent
+		var err error
+		if name, timestamp, ok := ScanEventHeader(b); ok {
+			if !ew.needEvent(string(name)) {
+				if match, needFields := ew.EventFilter.MatchName(string(name)); !match && !needFields {
 					return nil
 				}
 			}
+			raw = newRawEvent(b, name, timestamp)
+		} else if raw, err = decodeRawEvent(b); err != nil {
+			return nil
+		}
+		if ew.State != nil {
+			ew.State.Update(raw)
 		}
-		content, err := ParseJournalLine(b)
-		if err == nil {
-			if len(ew.EventFilter) > 0 && name == "" {
-				// Apparently the byte-based event name filtering failed, so
-				// filter based on the parsed representation.
-				name := GetEventName(content)
-				if name != "" {
-					if _, ok := ew.EventFilter[name]; !ok {
-						return nil
-					}
+		if raw.Event == "Commander" || raw.Event == "LoadGame" {
+			if content, err := raw.Json(); err == nil {
+				if name, ok := CommanderName(content); ok {
+					ew.commander = name
 				}
 			}
-			// Emit the event.
-			select {
-			case ew.Journals <- content:
-				/*noop*/
-			case <-ew.shutdown.Dying():
-				return ErrEWShutdown
+		}
+		if raw.Event == "Fileheader" {
+			if err := ew.startSession(raw); err != nil {
+				return err
+			}
+		}
+		if raw.Event == "Shutdown" {
+			// The session ends after the Shutdown event itself.
+			defer ew.endSession(raw.Timestamp)
+		}
+		if !ew.wantEvent(raw.Event, raw.Json) {
+			return nil
+		}
+
+		var content interface{} = raw
+		if !ew.RawEvents {
+			if content, err = raw.Json(); err != nil {
+				return nil
 			}
 		}
+
+		// Emit the event.
+		if !ew.send(content) {
+			return ErrEWShutdown
+		}
 		return nil
 	})
 }
 
+// needEvent reports whether the named journal event is used by the
+// watcher itself, and so must be decoded even when it is filtered.
+func (ew *EliteWatcher) needEvent(name string) bool {
+	switch name {
+	case "Fileheader", "Shutdown", "Commander", "LoadGame":
+		return true
+	}
+	return ew.State.Wants(name)
+}
+
+// startSession sends the session events for a journal Fileheader.
+func (ew *EliteWatcher) startSession(raw *RawEvent) error {
+	content, err := raw.Json()
+	if err != nil {
+		return nil
+	}
+	part, _ := content.Int64("part")
+	switch {
+	case part <= 1:
+		if err := ew.endSession(raw.Timestamp); err != nil {
+			return err
+		}
+		ew.session = &SessionEvent{SessionID: SessionID(ew.tail.Filename)}
+		ew.session.Event = "SessionStart"
+	case ew.session == nil:
+		// Started in the middle of a session, whose ID is that of its
+		// first part.
+		ew.session = &SessionEvent{SessionID: continuedSessionID(ew.tail.Filename)}
+		ew.session.Event = "SessionContinued"
+	default:
+		ew.session.Event = "SessionContinued"
+	}
+	ew.session.Timestamp = raw.Timestamp
+	ew.session.Part = int(part)
+	ew.session.Filename = ew.tail.Filename
+	return ew.sendSession(*ew.session)
+}
+
+// continuedSessionID returns the ID of the session that the journal
+// filename continues, as given by ListSessions.
+func continuedSessionID(filename string) string {
+	sessions, _ := ListSessions(filepath.Dir(filename))
+	for _, s := range sessions {
+		for _, part := range s.Parts {
+			if filepath.Base(part) == filepath.Base(filename) {
+				return s.ID
+			}
+		}
+	}
+	return SessionID(filename)
+}
+
+// endSession sends a SessionEnd event for the current session, if any.
+func (ew *EliteWatcher) endSession(timestamp string) error {
+	if ew.session == nil {
+		return nil
+	}
+	e := *ew.session
+	e.Event = "SessionEnd"
+	e.Timestamp = timestamp
+	ew.session = nil
+	return ew.sendSession(e)
+}
+
+func (ew *EliteWatcher) sendSession(e SessionEvent) error {
+	fields := func() (Json, error) {
+		return Json{
+			"timestamp": e.Timestamp,
+			"event":     e.Event,
+			"SessionID": e.SessionID,
+			"Part":      e.Part,
+			"Filename":  e.Filename,
+		}, nil
+	}
+	if !ew.wantEvent(e.Event, fields) {
+		return nil
+	}
+	if !ew.send(&e) {
+		return ErrEWShutdown
+	}
+	return nil
+}
+
+// send sends an event to Journals, wrapped in a SourcedEvent if
+// ew.Source is set. It returns false if the watcher is stopping.
+func (ew *EliteWatcher) send(e interface{}) bool {
+	if ew.Source != "" {
+		e = &SourcedEvent{Source: ew.Source, Commander: ew.commander, Event: e}
+	}
+	select {
+	case ew.Journals <- e:
+		return true
+	case <-ew.done:
+		return false
+	}
+}
+
+// sendIdle sends a GameIdle or GameResumed event, if there is one.
+func (ew *EliteWatcher) sendIdle(e *IdleEvent) {
+	if e != nil && ew.wantEvent(e.Event, e.fields) {
+		ew.send(e)
+	}
+}
+
+// wantEvent reports whether the named event passes ew.EventFilter.
+// fields is only called when a field predicate needs the decoded event.
+func (ew *EliteWatcher) wantEvent(name string, fields func() (Json, error)) bool {
+	match, needFields := ew.EventFilter.MatchName(name)
+	if needFields {
+		content, err := fields()
+		match = err == nil && ew.EventFilter.Match(name, content)
+	}
+	return match
+}
+
+// WatchStatusFiles restricts the status files that are read to names,
+// which may be either file names such as "status.json" or event names
+// such as "Status". It returns an error, and changes nothing, if a name
+// is not a registered status file.
+func (ew *EliteWatcher) WatchStatusFiles(names ...string) error {
+	for _, name := range names {
+		if _, ok := LookupStatusFile(statusFileKey(name)); !ok {
+			return errors.New("edgo: unknown status file " + name)
+		}
+	}
+	if ew.StatusFiles == nil {
+		ew.StatusFiles = make(map[string]struct{})
+	}
+	for _, name := range names {
+		ew.StatusFiles[statusFileKey(name)] = struct{}{}
+	}
+	return nil
+}
+
+func statusFileKey(name string) string {
+	if sf, ok := LookupStatusEvent(name); ok {
+		name = sf.Filename
+	}
+	key := strings.ToLower(filepath.Base(name))
+	if !strings.HasSuffix(key, ".json") {
+		key += ".json"
+	}
+	return key
+}
+
+// wantStatusFile reports whether filename passes ew.StatusFiles.
+func (ew *EliteWatcher) wantStatusFile(filename string) bool {
+	if len(ew.StatusFiles) == 0 {
+		return true
+	}
+	_, ok := ew.StatusFiles[statusFileKey(filename)]
+	return ok
+}
+
 func (ew *EliteWatcher) readAndParseStatusFile(filename string) {
+	if !ew.wantStatusFile(filename) {
+		return
+	}
+
 	// A status file is parsed directly by the watcher goroutine.
 	// though we could change that.
-	// TODO: Add event filter
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return
+	}
+
+	// The game rewrites status files with identical content but a new
+	// timestamp, and a single save may produce several write events.
+	fields, err := ParseJournalLine(data)
+	if err != nil {
+		return
+	}
+	key := statusFileKey(filename)
+	sum := statusSum(fields)
+	prev := ew.statusCache[key]
+	if prev != nil && prev.sum == sum {
+		return
+	}
+
+	var content interface{}
+	if content, err = ParseStatusContents(filename, data); err != nil {
+		return
+	}
+	if ew.State != nil {
+		ew.State.Update(content)
+	}
+	if st, ok := content.(*Status); ok {
+		// Changes are sent after the status itself, and are filtered
+		// by their own event names.
+		defer ew.sendStatusChanges(StatusChanges(ew.lastStatus, st))
+		ew.lastStatus = st
+	}
+	raw := &RawEvent{Raw: data}
+	if !ew.wantEvent(GetEventName(content), raw.Json) {
+		return
+	}
+
+	entry := &statusEntry{sum: sum}
+	if ew.StatusDiffs {
+		entry.fields = fields
+		if prev != nil && prev.fields != nil {
+			changed, removed := DiffStatus(prev.fields, entry.fields)
+			if len(changed) == 0 && len(removed) == 0 {
+				ew.statusCache[key] = entry
+				return
+			}
+			content = &StatusDiff{
+				Base: Base{
+					Timestamp: GetEventTimestamp(content),
+					Event:     GetEventName(content),
+				},
+				Filename: filename,
+				Changed:  changed,
+				Removed:  removed,
+			}
+		}
+	}
+	ew.statusCache[key] = entry
+
 	log.Println("status:", filename)
-	content, err := ParseStatusData(filename)
-	if err == nil {
-		select {
-		case <-ew.shutdown.Dying():
+	ew.send(content)
+}
+
+// statusSum returns a checksum of a decoded status file, leaving out its
+// timestamp.
+func statusSum(fields Json) [sha256.Size]byte {
+	content := make(Json, len(fields))
+	for k, v := range fields {
+		if k != "timestamp" {
+			content[k] = v
+		}
+	}
+	// Maps are marshalled with sorted keys, so equal content gives
+	// equal sums.
+	b, _ := json.Marshal(content)
+	return sha256.Sum256(b)
+}
+
+func (ew *EliteWatcher) sendStatusChanges(changes []*StatusChange) {
+	for _, c := range changes {
+		if ew.wantEvent(c.Event, c.fields) && !ew.send(c) {
 			return
-		case ew.Journals <- content:
 		}
 	}
 }
@@ -204,22 +508,62 @@ func (ew *EliteWatcher) maybeSetJournalFile(filename string) {
 
 // handleLoop is the goroutine that watches for changes to the
 // journal directory and handles those change events.
-func (ew *EliteWatcher) handleLoop() {
-	ew.watcher.AddWatch(ew.DataDirectory)
+func (ew *EliteWatcher) handleLoop(ctx context.Context) error {
+	dataDir, err := filepath.Abs(ew.DataDirectory)
+	if err != nil {
+		return err
+	}
+	// The watcher closes when Run is stopping, which may be before the
+	// directory is watched.
+	if ok, _, err := ew.watchDataDirectory(dataDir); err == watch.ErrWatcherClosed {
+		return nil
+	} else if err != nil {
+		return err
+	} else if !ok {
+		log.Println("waiting for journal directory: ", dataDir)
+	}
 
 	var event watch.Event
 	var ok bool
 	for {
 		select {
-		case <-ew.shutdown.Dying():
-			return
+		case <-ctx.Done():
+			return nil
 
 		case event, ok = <-ew.watcher.Events:
 			if !ok {
-				return
+				return nil
 			}
 		}
 
+		// Move the watch down when one of the directories leading to
+		// the data directory is created, and up when the watched
+		// directory goes. Removing a directory does not always send an
+		// event for the directory itself, so any removal below it is
+		// checked.
+		created := event.Op&watch.Create == watch.Create && isPathPrefix(event.Name, dataDir)
+		removed := event.Op&(watch.Remove|watch.Rename) != 0 && isPathPrefix(ew.watching, event.Name)
+		if created && ew.watching != dataDir || removed {
+			wasWatching := ew.watching == dataDir
+			ok, moved, err := ew.watchDataDirectory(dataDir)
+			if err == watch.ErrWatcherClosed {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if wasWatching && moved {
+				log.Println("journal directory removed: ", dataDir)
+			}
+			if ok && moved {
+				log.Println("journal directory created: ", dataDir)
+				if !ew.catchUp(ctx, dataDir) {
+					return nil
+				}
+			}
+		}
+		if ew.watching != dataDir || filepath.Dir(event.Name) != dataDir {
+			continue
+		}
 		base := strings.ToLower(filepath.Base(event.Name))
 
 		switch {
@@ -227,8 +571,8 @@ func (ew *EliteWatcher) handleLoop() {
 			if IsStatusFile(base) {
 				// A status file was written. Block until the event is received.
 				select {
-				case <-ew.shutdown.Dying():
-					return
+				case <-ctx.Done():
+					return nil
 				case ew.statuswrite <- event.Name:
 					/*noop*/
 				}
@@ -245,8 +589,8 @@ func (ew *EliteWatcher) handleLoop() {
 			if journalRE.MatchString(base) {
 				// A new journal file was created. Block until the event is received.
 				select {
-				case <-ew.shutdown.Dying():
-					return
+				case <-ctx.Done():
+					return nil
 				case ew.newjournal <- event.Name:
 					/*noop*/
 				}
@@ -256,11 +600,152 @@ func (ew *EliteWatcher) handleLoop() {
 	panic("unreachable")
 }
 
-func (ew *EliteWatcher) Main() {
-	ew.setupInitialJournalFile()
+// watchDataDirectory watches dataDir or, if it does not exist yet, the
+// nearest existing directory above it, so that its creation is seen.
+// The parent of the watched directory is watched too, so that its
+// removal is seen. It reports whether dataDir itself is watched, and
+// whether the watch has moved to another directory.
+func (ew *EliteWatcher) watchDataDirectory(dataDir string) (watched, moved bool, err error) {
+	for {
+		dir := dataDir
+		var fi os.FileInfo
+		for {
+			if fi, err = os.Stat(dir); err == nil && fi.IsDir() {
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				return false, moved, os.ErrNotExist
+			}
+			dir = parent
+		}
+		// A directory that was removed and created again needs a new
+		// watch.
+		if dir == ew.watching && os.SameFile(fi, ew.watchInfo) {
+			return dir == dataDir, moved, nil
+		}
+
+		ew.unwatchDataDirectory()
+		moved = true
+		if err := ew.watcher.AddWatch(dir); err != nil {
+			if os.IsNotExist(err) {
+				// Removed since it was found; look again.
+				continue
+			}
+			return false, moved, err
+		}
+		ew.watching, ew.watchInfo = dir, fi
+		if parent := filepath.Dir(dir); parent != dir {
+			if err := ew.watcher.AddWatch(parent); err != nil && !os.IsNotExist(err) {
+				return false, moved, err
+			}
+			ew.watchParent = parent
+		}
+		// Look again, in case a directory below was created, or this
+		// one removed, before the watch was added.
+	}
+}
+
+// unwatchDataDirectory removes the watches added by watchDataDirectory.
+// Watches of removed directories are already gone, so errors are
+// ignored.
+func (ew *EliteWatcher) unwatchDataDirectory() {
+	if ew.watching != "" {
+		ew.watcher.RemoveWatch(ew.watching)
+	}
+	if ew.watchParent != "" {
+		ew.watcher.RemoveWatch(ew.watchParent)
+	}
+	ew.watching, ew.watchInfo, ew.watchParent = "", nil, ""
+}
+
+// isPathPrefix reports whether dir is path or one of its parents.
+func isPathPrefix(dir, path string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
 
-	go ew.fileTailer()
-	go ew.handleLoop()
+// catchUp sends the latest journal and the status files in a directory
+// that has just appeared, which may have been written before it was
+// watched. It returns false if ctx is done.
+func (ew *EliteWatcher) catchUp(ctx context.Context, dataDir string) bool {
+	if name, _ := latestJournal(dataDir); name != "" {
+		select {
+		case ew.newjournal <- filepath.Join(dataDir, name):
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for _, sf := range StatusFiles() {
+		filename := filepath.Join(dataDir, sf.Filename)
+		if _, err := os.Stat(filename); err != nil {
+			continue
+		}
+		select {
+		case ew.statuswrite <- filename:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// Run watches the journal directory and sends events to Journals until
+// ctx is done, Close is called or a goroutine fails. It returns the
+// first error, once every goroutine has exited and Journals has been
+// closed. Run must only be called once.
+func (ew *EliteWatcher) Run(ctx context.Context) error {
+	ew.mu.Lock()
+	ew.running = true
+	ew.mu.Unlock()
+	defer close(ew.stopped)
+	defer close(ew.Journals)
 
-	ew.watcher.RunLoop(ew.shutdown)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ew.done = ctx.Done()
+
+	if ew.State != nil {
+		// Tag events before the first Commander event with the
+		// restored commander.
+		ew.commander = ew.State.Snapshot().Commander
+	}
+	if err := ew.setupInitialJournalFile(); err != nil && !os.IsNotExist(err) {
+		log.Println("set journal: ", err)
+	}
+
+	// When any goroutine returns, the others are stopped too.
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	run := func(f func(context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f(ctx); err != nil {
+				errOnce.Do(func() { firstErr = err })
+			}
+			cancel()
+		}()
+	}
+	run(ew.watcher.Run)
+	run(ew.handleLoop)
+	run(ew.fileTailer)
+
+	select {
+	case <-ctx.Done():
+	case <-ew.stop:
+		cancel()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// Main runs the watcher until ew's shutdown is dying, and kills it with
+// any error. It is kept for callers using watch.Shutdown; see Run.
+func (ew *EliteWatcher) Main() {
+	ctx, cancel := watch.WithShutdown(context.Background(), ew.shutdown)
+	defer cancel()
+	if err := ew.Run(ctx); err != nil {
+		ew.shutdown.Kill(err)
+	}
 }
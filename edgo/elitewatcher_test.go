@@ -0,0 +1,199 @@
+package edgo
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatusFileDedup(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "Cargo.json")
+	ew := NewEliteWatcher(dir, nil)
+
+	for _, tt := range []struct {
+		content string
+		sent    bool
+	}{
+		{`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Cargo", "Vessel":"Ship", "Count":0, "Inventory":[ ] }`, true},
+		// Only the timestamp changed.
+		{`{ "timestamp":"2025-01-01T12:00:05Z", "event":"Cargo", "Vessel":"Ship", "Count":0, "Inventory":[ ] }`, false},
+		{`{ "timestamp":"2025-01-01T12:00:05Z", "event":"Cargo", "Vessel":"Ship", "Count":0, "Inventory":[ ] }`, false},
+		{`{ "timestamp":"2025-01-01T12:00:09Z", "event":"Cargo", "Vessel":"Ship", "Count":1, "Inventory":[ { "Name":"gold", "Count":1, "Stolen":0 } ] }`, true},
+	} {
+		if err := ioutil.WriteFile(filename, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		ew.readAndParseStatusFile(filename)
+		var sent bool
+		select {
+		case e := <-ew.Journals:
+			sent = GetEventName(e) == "Cargo"
+		default:
+		}
+		if sent != tt.sent {
+			t.Errorf("%s: sent %v, want %v", tt.content, sent, tt.sent)
+		}
+	}
+}
+
+func TestWatchStatusFiles(t *testing.T) {
+	ew := NewEliteWatcher(t.TempDir(), nil)
+	if err := ew.WatchStatusFiles("Status", "cargo.json", "Statsu"); err == nil {
+		t.Error("an unknown status file was accepted")
+	}
+	if len(ew.StatusFiles) != 0 {
+		t.Errorf("status files %v after an error, want none", ew.StatusFiles)
+	}
+
+	if err := ew.WatchStatusFiles("Status", "cargo.json", "ModuleInfo"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		filename string
+		want     bool
+	}{
+		{"Status.json", true},
+		{"Cargo.json", true},
+		{"ModulesInfo.json", true},
+		{"NavRoute.json", false},
+	} {
+		if got := ew.wantStatusFile(tt.filename); got != tt.want {
+			t.Errorf("%s: read %v, want %v", tt.filename, got, tt.want)
+		}
+	}
+}
+
+// runWatcher runs a watcher for dir, sending the events matching
+// filters, until the test ends.
+func runWatcher(t *testing.T, dir string, filters ...string) *EliteWatcher {
+	t.Helper()
+	ew := NewEliteWatcher(dir, nil)
+	ew.EventFilter, _ = CompileFilter(filters, nil)
+	errc := make(chan error, 1)
+	go func() { errc <- ew.Run(context.Background()) }()
+	t.Cleanup(func() {
+		ew.Close()
+		if err := <-errc; err != nil {
+			t.Error(err)
+		}
+	})
+	return ew
+}
+
+// expectEvent waits for the Test event numbered n, skipping earlier ones.
+func expectEvent(t *testing.T, ew *EliteWatcher, n int64) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-ew.Journals:
+			if j, ok := e.(Json); ok {
+				if got, _ := j.Int64("N"); got == n {
+					return
+				} else if got > n {
+					t.Fatalf("got event %v, want %d", j, n)
+				}
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for event %d", n)
+		}
+	}
+}
+
+// waitWatched appends the Test event numbered n to a journal in dir
+// until the watcher sends it, which it only does once the directory is
+// watched.
+func waitWatched(t *testing.T, ew *EliteWatcher, dir, name string, n int) {
+	t.Helper()
+	for i := 0; i < 50; i++ {
+		appendJournal(t, dir, name, testEvent(n))
+		select {
+		case e := <-ew.Journals:
+			if j, ok := e.(Json); ok {
+				if got, _ := j.Int64("N"); got == int64(n) {
+					return
+				}
+			}
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	t.Fatalf("%s is not watched", dir)
+}
+
+func appendJournal(t *testing.T, dir, name, line string) {
+	t.Helper()
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testEvent(n int) string {
+	return fmt.Sprintf(`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Test", "N":%d }`, n)
+}
+
+func TestWatchDirectoryCreated(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b", "journals")
+	ew := runWatcher(t, dir, "Test")
+	// Let the watcher start waiting for the directory.
+	time.Sleep(100 * time.Millisecond)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeJournal(t, dir, "Journal.2025-01-01T120000.01.log", testEvent(1))
+	expectEvent(t, ew, 1)
+}
+
+func TestWatchDirectoryRecreated(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		remove string // relative to the temporary directory
+	}{
+		{"directory", "a/b"},
+		{"ancestor", "a"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			dir := filepath.Join(tmp, "a", "b")
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			writeJournal(t, dir, "Journal.2025-01-01T120000.01.log", testEvent(1))
+			ew := runWatcher(t, dir, "Test")
+			expectEvent(t, ew, 1)
+			waitWatched(t, ew, dir, "Journal.2025-01-01T120000.01.log", 2)
+
+			if err := os.RemoveAll(filepath.Join(tmp, filepath.FromSlash(tt.remove))); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(100 * time.Millisecond)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			writeJournal(t, dir, "Journal.2025-01-01T130000.01.log", testEvent(3))
+			expectEvent(t, ew, 3)
+		})
+	}
+}
+
+func TestWatcherCloseAtStart(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		ew := NewEliteWatcher(dir, nil)
+		errc := make(chan error, 1)
+		go func() { errc <- ew.Run(context.Background()) }()
+		ew.Close()
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
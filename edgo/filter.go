@@ -0,0 +1,191 @@
+package edgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Filter selects events by name and, optionally, by field values.
+// A nil *Filter matches every event.
+//
+// Each pattern is a path.Match glob over the event name, ignoring case
+// so that Fsd* matches FSDJump, optionally followed by a colon and a
+// comma separated list of field predicates:
+//
+//	FSDJump
+//	Fsd*
+//	ReceiveText:Channel=player
+//	Status:Destination.Name!=,LegalState=Wanted
+//
+// Predicate values are globs too. An event is kept when it matches no
+// exclude pattern and, if any include patterns are given, at least one
+// include pattern.
+type Filter struct {
+	include []filterRule
+	exclude []filterRule
+}
+
+type filterRule struct {
+	pattern string
+	preds   []fieldPredicate
+}
+
+type fieldPredicate struct {
+	path   []string
+	value  string
+	negate bool
+}
+
+// CompileFilter compiles include and exclude patterns into a Filter.
+func CompileFilter(include, exclude []string) (*Filter, error) {
+	f := &Filter{}
+	for _, p := range include {
+		r, err := compileFilterRule(p)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, r)
+	}
+	for _, p := range exclude {
+		r, err := compileFilterRule(p)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, r)
+	}
+	return f, nil
+}
+
+func compileFilterRule(spec string) (filterRule, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	r := filterRule{pattern: strings.ToLower(parts[0])}
+	if _, err := path.Match(r.pattern, ""); err != nil || r.pattern == "" {
+		return r, fmt.Errorf("filter: bad event pattern %q", spec)
+	}
+	if len(parts) == 1 {
+		return r, nil
+	}
+	for _, p := range strings.Split(parts[1], ",") {
+		var pred fieldPredicate
+		idx := strings.Index(p, "=")
+		if idx <= 0 {
+			return r, fmt.Errorf("filter: bad field predicate %q in %q", p, spec)
+		}
+		field, value := p[:idx], p[idx+1:]
+		if strings.HasSuffix(field, "!") {
+			field = field[:len(field)-1]
+			pred.negate = true
+		}
+		if field == "" {
+			return r, fmt.Errorf("filter: bad field predicate %q in %q", p, spec)
+		}
+		if _, err := path.Match(value, ""); err != nil {
+			return r, fmt.Errorf("filter: bad field pattern %q in %q", value, spec)
+		}
+		pred.path = strings.Split(field, ".")
+		pred.value = value
+		r.preds = append(r.preds, pred)
+	}
+	return r, nil
+}
+
+// MatchName decides whether the named event passes the filter using
+// only its name. When needFields is true the decision depends on field
+// predicates, and Match must be called with the decoded event.
+func (f *Filter) MatchName(name string) (match, needFields bool) {
+	if f == nil {
+		return true, false
+	}
+	name = strings.ToLower(name)
+	for _, r := range f.exclude {
+		if r.matchName(name) {
+			if len(r.preds) == 0 {
+				return false, false
+			}
+			needFields = true
+		}
+	}
+	if len(f.include) == 0 {
+		return !needFields, needFields
+	}
+	matched := false
+	for _, r := range f.include {
+		if r.matchName(name) {
+			if len(r.preds) == 0 {
+				return !needFields, needFields
+			}
+			matched = true
+		}
+	}
+	return false, matched
+}
+
+// Match reports whether the named event with the given content passes
+// the filter.
+func (f *Filter) Match(name string, content Json) bool {
+	if f == nil {
+		return true
+	}
+	name = strings.ToLower(name)
+	for _, r := range f.exclude {
+		if r.match(name, content) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, r := range f.include {
+		if r.match(name, content) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchName matches a lower case event name.
+func (r *filterRule) matchName(name string) bool {
+	ok, _ := path.Match(r.pattern, name)
+	return ok
+}
+
+func (r *filterRule) match(name string, content Json) bool {
+	if !r.matchName(name) {
+		return false
+	}
+	for _, p := range r.preds {
+		if !p.match(content) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *fieldPredicate) match(content Json) bool {
+	v, ok := content.Lookup(p.path...)
+	if !ok {
+		return p.negate
+	}
+	ok, _ = path.Match(p.value, filterValueString(v))
+	return ok != p.negate
+}
+
+func filterValueString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(t)
+	}
+}
@@ -0,0 +1,96 @@
+package edgo
+
+import "testing"
+
+func TestCompileFilterErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"[",
+		"ReceiveText:",
+		"ReceiveText:Channel",
+		"ReceiveText:=player",
+		"ReceiveText:!=player",
+		"ReceiveText:Channel=[",
+	} {
+		if _, err := CompileFilter([]string{spec}, nil); err == nil {
+			t.Errorf("CompileFilter(%q) did not fail", spec)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	text, _ := ParseJournalLine([]byte(`{ "timestamp":"2025-01-01T12:00:00Z", "event":"ReceiveText", "From":"Pete", "Channel":"player" }`))
+	local, _ := ParseJournalLine([]byte(`{ "timestamp":"2025-01-01T12:00:00Z", "event":"ReceiveText", "From":"Sol", "Channel":"local" }`))
+	jump, _ := ParseJournalLine([]byte(`{ "timestamp":"2025-01-01T12:00:00Z", "event":"FSDJump", "StarSystem":"Sol", "JumpDist":12.5, "Taxi":false }`))
+	status, _ := ParseJournalLine([]byte(`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Status", "LegalState":"Wanted", "Destination":{ "Name":"Sol" } }`))
+
+	tests := []struct {
+		name             string
+		include, exclude []string
+		event            Json
+		want             bool
+	}{
+		{"no patterns", nil, nil, jump, true},
+		{"exact", []string{"FSDJump"}, nil, jump, true},
+		{"other event", []string{"FSDJump"}, nil, text, false},
+		{"glob ignores case", []string{"Fsd*"}, nil, jump, true},
+		{"lower case", []string{"fsdjump"}, nil, jump, true},
+		{"excluded", nil, []string{"fsd*"}, jump, false},
+		{"exclude wins", []string{"*"}, []string{"FSDJump"}, jump, false},
+		{"field", []string{"ReceiveText:Channel=player"}, nil, text, true},
+		{"field mismatch", []string{"ReceiveText:Channel=player"}, nil, local, false},
+		{"field value glob", []string{"ReceiveText:From=P*"}, nil, text, true},
+		{"field values keep case", []string{"ReceiveText:From=pete"}, nil, text, false},
+		{"number", []string{"FSDJump:JumpDist=12.5"}, nil, jump, true},
+		{"bool", []string{"FSDJump:Taxi=false"}, nil, jump, true},
+		{"negated", []string{"ReceiveText:Channel!=local"}, nil, text, true},
+		{"negated mismatch", []string{"ReceiveText:Channel!=local"}, nil, local, false},
+		{"negated missing field", []string{"FSDJump:Boost!=*"}, nil, jump, true},
+		{"nested field", []string{"Status:Destination.Name=Sol"}, nil, status, true},
+		{"all predicates", []string{"Status:Destination.Name!=,LegalState=Wanted"}, nil, status, true},
+		{"one predicate fails", []string{"Status:Destination.Name!=,LegalState=Clean"}, nil, status, false},
+		{"exclude by field", nil, []string{"ReceiveText:Channel=local"}, local, false},
+		{"exclude by field mismatch", nil, []string{"ReceiveText:Channel=local"}, text, true},
+	}
+	for _, tt := range tests {
+		f, err := CompileFilter(tt.include, tt.exclude)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		name := GetEventName(tt.event)
+		if got := f.Match(name, tt.event); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+		// MatchName must agree with Match when it can decide alone.
+		if match, needFields := f.MatchName(name); !needFields && match != tt.want {
+			t.Errorf("%s: MatchName = %v, want %v", tt.name, match, tt.want)
+		}
+	}
+
+	var nilFilter *Filter
+	if !nilFilter.Match("FSDJump", jump) {
+		t.Error("nil filter does not match")
+	}
+}
+
+func TestFilterMatchName(t *testing.T) {
+	f, err := CompileFilter([]string{"Fsd*", "ReceiveText:Channel=player"}, []string{"Music"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name              string
+		match, needFields bool
+	}{
+		{"FSDJump", true, false},
+		{"ReceiveText", false, true},
+		{"Music", false, false},
+		{"Docked", false, false},
+	}
+	for _, tt := range tests {
+		match, needFields := f.MatchName(tt.name)
+		if match != tt.match || needFields != tt.needFields {
+			t.Errorf("MatchName(%q) = %v, %v; want %v, %v", tt.name, match, needFields, tt.match, tt.needFields)
+		}
+	}
+}
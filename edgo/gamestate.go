@@ -0,0 +1,459 @@
+package edgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// State is a snapshot of the game, reconstructed from journal and
+// status events.
+type State struct {
+	Timestamp string `json:"timestamp"` // of the latest event that changed the state
+
+	Commander string `json:"Commander,omitempty"`
+	FID       string `json:"FID,omitempty"`
+
+	Ship          string          `json:"Ship,omitempty"`
+	ShipName      string          `json:"ShipName,omitempty"`
+	ShipIdent     string          `json:"ShipIdent,omitempty"`
+	ShipID        int64           `json:"ShipID,omitempty"`
+	Modules       []LoadoutModule `json:"Modules,omitempty"`
+	CargoCapacity int64           `json:"CargoCapacity,omitempty"`
+	FuelCapacity  float64         `json:"FuelCapacity,omitempty"`
+
+	StarSystem    string     `json:"StarSystem,omitempty"`
+	SystemAddress int64      `json:"SystemAddress,omitempty"`
+	StarPos       [3]float64 `json:"StarPos"`
+	Body          string     `json:"Body,omitempty"`
+	Station       string     `json:"Station,omitempty"`
+	MarketID      int64      `json:"MarketID,omitempty"`
+
+	Docked      bool `json:"Docked"`
+	Landed      bool `json:"Landed"`
+	Supercruise bool `json:"Supercruise"`
+	OnFoot      bool `json:"OnFoot"`
+
+	FuelMain      float64 `json:"FuelMain"`
+	FuelReservoir float64 `json:"FuelReservoir"`
+	Cargo         float64 `json:"Cargo"`
+	Hull          float64 `json:"Hull"` // 0.0 - 1.0
+	LegalState    string  `json:"LegalState,omitempty"`
+
+	NavRoute []NavRouteEntry `json:"NavRoute,omitempty"`
+}
+
+// LoadoutModule is a module fitted to the current ship.
+type LoadoutModule struct {
+	Slot     string  `json:"Slot"`
+	Item     string  `json:"Item"`
+	On       bool    `json:"On"`
+	Priority int64   `json:"Priority"`
+	Health   float64 `json:"Health"`
+}
+
+func (s *State) copy() State {
+	c := *s
+	c.Modules = append([]LoadoutModule(nil), s.Modules...)
+	c.NavRoute = append([]NavRouteEntry(nil), s.NavRoute...)
+	return c
+}
+
+// GameState tracks State as events are applied by Update. It is safe
+// for concurrent use; EliteWatcher updates it when set as its State.
+type GameState struct {
+	mu       sync.RWMutex
+	state    State          // guarded by mu
+	notify   []chan<- State // guarded by mu
+	restored string         // guarded by mu; timestamp of the loaded state
+}
+
+func NewGameState() *GameState {
+	return &GameState{}
+}
+
+// Snapshot returns a copy of the current state.
+func (gs *GameState) Snapshot() State {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.state.copy()
+}
+
+// Notify causes a snapshot to be sent to c whenever the state changes.
+// Like signal.Notify, sends do not block, so c should be buffered.
+func (gs *GameState) Notify(c chan<- State) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.notify = append(gs.notify, c)
+}
+
+// Stop stops sending state changes to c.
+func (gs *GameState) Stop(c chan<- State) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	for i, n := range gs.notify {
+		if n == c {
+			gs.notify = append(gs.notify[:i], gs.notify[i+1:]...)
+			return
+		}
+	}
+}
+
+// gameStateEvents are the journal events that Update handles.
+var gameStateEvents = map[string]struct{}{
+	"ApproachBody":     {},
+	"CarrierJump":      {},
+	"Commander":        {},
+	"Disembark":        {},
+	"Docked":           {},
+	"Embark":           {},
+	"FSDJump":          {},
+	"FuelScoop":        {},
+	"HullDamage":       {},
+	"LeaveBody":        {},
+	"Liftoff":          {},
+	"LoadGame":         {},
+	"Loadout":          {},
+	"Location":         {},
+	"NavRouteClear":    {},
+	"RefuelAll":        {},
+	"Repair":           {},
+	"RepairAll":        {},
+	"ShipyardSwap":     {},
+	"SupercruiseEntry": {},
+	"SupercruiseExit":  {},
+	"Touchdown":        {},
+	"Undocked":         {},
+}
+
+// Wants reports whether Update uses the named journal event. It may be
+// called on a nil *GameState.
+func (gs *GameState) Wants(name string) bool {
+	if gs == nil {
+		return false
+	}
+	_, ok := gameStateEvents[name]
+	return ok
+}
+
+// Update applies an event, as sent on EliteWatcher.Journals, and
+// reports whether the state changed. Status files are read apart from
+// the journal, so a status older than the state is ignored.
+func (gs *GameState) Update(e interface{}) bool {
+	if r, ok := e.(*RawEvent); ok {
+		if !gs.Wants(r.Event) {
+			return false
+		}
+		var err error
+		if e, err = r.Json(); err != nil {
+			return false
+		}
+	}
+
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	prev := gs.state.copy()
+	switch v := e.(type) {
+	case Json:
+		if t, _ := v.String("timestamp"); t < gs.restored {
+			// Already part of the loaded state.
+			return false
+		}
+		gs.state.applyJournal(v)
+	case *Status:
+		if v.Timestamp < gs.state.Timestamp {
+			// Older than a journal event already applied.
+			return false
+		}
+		gs.state.applyStatus(v)
+	case *StatusDiff:
+		if v.Event == "Status" && v.Timestamp >= gs.state.Timestamp {
+			gs.state.applyStatusDiff(v)
+		}
+	case *Cargo:
+		if v.Vessel == "" || v.Vessel == "Ship" {
+			var count int64
+			for _, item := range v.Inventory {
+				count += item.Count
+			}
+			gs.state.Cargo = float64(count)
+		}
+	case *NavRoute:
+		gs.state.NavRoute = append([]NavRouteEntry(nil), v.Route...)
+	default:
+		return false
+	}
+
+	if reflect.DeepEqual(prev, gs.state.copy()) {
+		return false
+	}
+	if t := GetEventTimestamp(e); t > gs.state.Timestamp {
+		gs.state.Timestamp = t
+	}
+	snapshot := gs.state.copy()
+	for _, c := range gs.notify {
+		select {
+		case c <- snapshot:
+		default:
+		}
+	}
+	return true
+}
+
+func (s *State) applyJournal(j Json) {
+	name, _ := j.String("event")
+	switch name {
+	case "Commander":
+		s.Commander, _ = j.String("Name")
+		s.FID, _ = j.String("FID")
+
+	case "LoadGame":
+		s.Commander, _ = j.String("Commander")
+		s.FID, _ = j.String("FID")
+		s.applyShip(j, "Ship")
+		s.FuelMain, _ = j.Float("FuelLevel")
+		s.FuelCapacity, _ = j.Float("FuelCapacity")
+
+	case "Location", "FSDJump", "CarrierJump":
+		s.StarSystem, _ = j.String("StarSystem")
+		s.SystemAddress, _ = j.Int64("SystemAddress")
+		for i := range s.StarPos {
+			s.StarPos[i], _ = j.Float("StarPos", strconv.Itoa(i))
+		}
+		s.Body, _ = j.String("Body")
+		s.Docked, _ = j.Bool("Docked")
+		s.Station, s.MarketID = "", 0
+		if s.Docked {
+			s.Station, _ = j.String("StationName")
+			s.MarketID, _ = j.Int64("MarketID")
+		}
+		if name == "FSDJump" {
+			s.Supercruise = true
+			s.Landed = false
+			if fuel, ok := j.Float("FuelLevel"); ok {
+				s.FuelMain = fuel
+			}
+		}
+
+	case "Docked":
+		s.Docked = true
+		s.Supercruise = false
+		if system, ok := j.String("StarSystem"); ok {
+			s.StarSystem = system
+		}
+		s.Station, _ = j.String("StationName")
+		s.MarketID, _ = j.Int64("MarketID")
+
+	case "Undocked":
+		s.Docked = false
+		s.Station, s.MarketID = "", 0
+
+	case "Touchdown":
+		if player, ok := j.Bool("PlayerControlled"); !ok || player {
+			s.Landed = true
+		}
+
+	case "Liftoff":
+		if player, ok := j.Bool("PlayerControlled"); !ok || player {
+			s.Landed = false
+		}
+
+	case "SupercruiseEntry":
+		s.Supercruise = true
+
+	case "SupercruiseExit":
+		s.Supercruise = false
+		s.Body, _ = j.String("Body")
+
+	case "ApproachBody":
+		s.Body, _ = j.String("Body")
+
+	case "LeaveBody":
+		s.Body = ""
+
+	case "Embark":
+		s.OnFoot = false
+
+	case "Disembark":
+		s.OnFoot = true
+
+	case "Loadout":
+		s.applyShip(j, "Ship")
+		s.Hull, _ = j.Float("HullHealth")
+		s.CargoCapacity, _ = j.Int64("CargoCapacity")
+		s.FuelCapacity, _ = j.Float("FuelCapacity", "Main")
+		s.Modules = nil
+		modules, _ := j.Array("Modules")
+		for i := range modules {
+			m, ok := j.Object("Modules", strconv.Itoa(i))
+			if !ok {
+				continue
+			}
+			var lm LoadoutModule
+			lm.Slot, _ = m.String("Slot")
+			lm.Item, _ = m.String("Item")
+			lm.On, _ = m.Bool("On")
+			lm.Priority, _ = m.Int64("Priority")
+			lm.Health, _ = m.Float("Health")
+			s.Modules = append(s.Modules, lm)
+		}
+
+	case "ShipyardSwap":
+		s.Ship, _ = j.String("ShipType")
+		s.ShipID, _ = j.Int64("ShipID")
+		s.ShipName, s.ShipIdent, s.Modules = "", "", nil
+
+	case "HullDamage":
+		if player, ok := j.Bool("PlayerPilot"); !ok || player {
+			s.Hull, _ = j.Float("Health")
+		}
+
+	case "Repair", "RepairAll":
+		s.Hull = 1
+
+	case "FuelScoop":
+		s.FuelMain, _ = j.Float("Total")
+
+	case "RefuelAll":
+		s.FuelMain = s.FuelCapacity
+
+	case "NavRouteClear":
+		s.NavRoute = nil
+	}
+}
+
+func (s *State) applyShip(j Json, key string) {
+	ship, _ := j.String(key)
+	shipID, _ := j.Int64("ShipID")
+	if ship != s.Ship || shipID != s.ShipID {
+		s.Modules = nil
+	}
+	s.Ship, s.ShipID = ship, shipID
+	s.ShipName, _ = j.String("ShipName")
+	s.ShipIdent, _ = j.String("ShipIdent")
+}
+
+func (s *State) applyStatus(st *Status) {
+	s.Docked = st.Flags&FlagDocked != 0
+	s.Landed = st.Flags&FlagLanded != 0
+	s.Supercruise = st.Flags&FlagSupercruise != 0
+	s.OnFoot = st.OnFoot()
+	s.LegalState = st.LegalState
+	if main, reservoir, ok := st.ShipFuel(); ok {
+		s.FuelMain, s.FuelReservoir = main, reservoir
+		s.Cargo = st.Cargo
+	}
+	if st.BodyName != "" {
+		s.Body = st.BodyName
+	}
+}
+
+func (s *State) applyStatusDiff(d *StatusDiff) {
+	if flags, ok := d.Changed.Int64("Flags"); ok {
+		s.Docked = flags&FlagDocked != 0
+		s.Landed = flags&FlagLanded != 0
+		s.Supercruise = flags&FlagSupercruise != 0
+	}
+	if flags, ok := d.Changed.Int64("Flags2"); ok {
+		s.OnFoot = flags&Flag2OnFoot != 0
+	}
+	if legal, ok := d.Changed.String("LegalState"); ok {
+		s.LegalState = legal
+	}
+	if fuel, ok := d.Changed.Float("Fuel", "FuelMain"); ok {
+		s.FuelMain = fuel
+	}
+	if fuel, ok := d.Changed.Float("Fuel", "FuelReservoir"); ok {
+		s.FuelReservoir = fuel
+	}
+	if cargo, ok := d.Changed.Float("Cargo"); ok {
+		s.Cargo = cargo
+	}
+	if body, ok := d.Changed.String("BodyName"); ok && body != "" {
+		s.Body = body
+	}
+}
+
+const gameStateVersion = 1
+
+var ErrGameStateVersion = errors.New("gamestate: unsupported version")
+
+// gameStateFile is the on-disk form of a GameState.
+type gameStateFile struct {
+	Version int    `json:"version"`
+	Saved   string `json:"saved"`
+	State   State  `json:"state"`
+}
+
+// Save writes the state to filename. The file is replaced atomically.
+func (gs *GameState) Save(filename string) error {
+	data, err := json.MarshalIndent(&gameStateFile{
+		Version: gameStateVersion,
+		Saved:   time.Now().UTC().Format(time.RFC3339),
+		State:   gs.Snapshot(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// Load replaces the state with one written by Save. Journal events older
+// than the loaded state are ignored by Update, so the journal tail can be
+// replayed on top of it.
+func (gs *GameState) Load(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var f gameStateFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	if f.Version != gameStateVersion {
+		return ErrGameStateVersion
+	}
+
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.state = f.State
+	gs.restored = f.State.Timestamp
+	return nil
+}
+
+// AutoSave saves the state to filename every interval, when it has
+// changed, until ctx is done. A final Save should only be made once it
+// has returned, as both write the same temporary file.
+func (gs *GameState) AutoSave(ctx context.Context, filename string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var saved string
+	for {
+		select {
+		case <-ticker.C:
+			if t := gs.Snapshot().Timestamp; t != saved {
+				if err := gs.Save(filename); err != nil {
+					log.Println("gamestate:", err)
+					continue
+				}
+				saved = t
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package edgo
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGameStateWants(t *testing.T) {
+	var nilState *GameState
+	if nilState.Wants("Docked") {
+		t.Error("nil state wants Docked")
+	}
+	gs := NewGameState()
+	if !gs.Wants("Docked") {
+		t.Error("state does not want Docked")
+	}
+	if gs.Wants("Scan") {
+		t.Error("state wants Scan")
+	}
+
+	// A watcher without a state still decodes its own events.
+	ew := &EliteWatcher{}
+	if !ew.needEvent("LoadGame") || ew.needEvent("Docked") {
+		t.Error("needEvent without a state")
+	}
+}
+
+func journalEvent(t *testing.T, line string) Json {
+	t.Helper()
+	j, err := ParseJournalLine([]byte(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func TestGameStateSaveLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+	gs := NewGameState()
+	for _, line := range []string{
+		`{ "timestamp":"2025-01-01T12:00:00Z", "event":"LoadGame", "Commander":"Jameson", "FID":"F1", "Ship":"SideWinder", "ShipID":1, "FuelLevel":2, "FuelCapacity":2 }`,
+		`{ "timestamp":"2025-01-01T12:00:01Z", "event":"Location", "StarSystem":"Sol", "SystemAddress":10477373803, "StarPos":[0,0,0], "Docked":true, "StationName":"Abraham Lincoln", "MarketID":128016640 }`,
+	} {
+		gs.Update(journalEvent(t, line))
+	}
+	if err := gs.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filename + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	// After a restart, the journal is replayed from its start on top of
+	// the loaded state.
+	tests := []struct {
+		name    string
+		line    string
+		changed bool
+	}{
+		{"older", `{ "timestamp":"2025-01-01T11:59:00Z", "event":"Undocked", "StationName":"Abraham Lincoln" }`, false},
+		{"replayed", `{ "timestamp":"2025-01-01T12:00:00Z", "event":"Commander", "Name":"Jameson", "FID":"F1" }`, false},
+		{"same second", `{ "timestamp":"2025-01-01T12:00:01Z", "event":"Undocked", "StationName":"Abraham Lincoln" }`, true},
+		{"newer", `{ "timestamp":"2025-01-01T12:05:00Z", "event":"Undocked", "StationName":"Abraham Lincoln" }`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded := NewGameState()
+			if err := loaded.Load(filename); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := loaded.Snapshot(), gs.Snapshot(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("loaded %+v, want %+v", got, want)
+			}
+			if changed := loaded.Update(journalEvent(t, tt.line)); changed != tt.changed {
+				t.Errorf("Update changed the state: %v, want %v", changed, tt.changed)
+			}
+			if docked := loaded.Snapshot().Docked; docked == tt.changed {
+				t.Errorf("Docked %v", docked)
+			}
+		})
+	}
+
+	if err := ioutil.WriteFile(filename, []byte(`{ "version":99, "state":{} }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewGameState().Load(filename); err != ErrGameStateVersion {
+		t.Errorf("Load of a newer version: %v", err)
+	}
+}
+
+func TestGameStateAutoSave(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+	gs := NewGameState()
+	gs.Update(journalEvent(t, `{ "timestamp":"2025-01-01T12:00:00Z", "event":"Commander", "Name":"Jameson", "FID":"F1" }`))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		gs.AutoSave(ctx, filename, time.Millisecond)
+		close(done)
+	}()
+	for i := 0; ; i++ {
+		if _, err := os.Stat(filename); err == nil {
+			break
+		} else if i == 500 {
+			t.Fatal("state not saved")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	loaded := NewGameState()
+	if err := loaded.Load(filename); err != nil {
+		t.Fatal(err)
+	}
+	if c := loaded.Snapshot().Commander; c != "Jameson" {
+		t.Errorf("loaded commander %q", c)
+	}
+}
+
+func statusEvent(t *testing.T, line string) *Status {
+	t.Helper()
+	st, err := ParseStatusContents("Status.json", []byte(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st.(*Status)
+}
+
+func TestGameStateUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []string // journal lines, or status lines prefixed with "status "
+		check  func(s State) bool
+	}{
+		{"location docked", []string{
+			`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Location", "StarSystem":"Sol", "SystemAddress":10477373803, "StarPos":[0,0,0], "Body":"Earth", "Docked":true, "StationName":"Abraham Lincoln", "MarketID":128016640 }`,
+		}, func(s State) bool {
+			return s.StarSystem == "Sol" && s.SystemAddress == 10477373803 && s.Docked &&
+				s.Station == "Abraham Lincoln" && s.MarketID == 128016640 && s.Body == "Earth"
+		}},
+		{"undocked", []string{
+			`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Docked", "StarSystem":"Sol", "StationName":"Abraham Lincoln", "MarketID":128016640 }`,
+			`{ "timestamp":"2025-01-01T12:01:00Z", "event":"Undocked", "StationName":"Abraham Lincoln" }`,
+		}, func(s State) bool {
+			return s.StarSystem == "Sol" && !s.Docked && s.Station == "" && s.MarketID == 0
+		}},
+		{"jump", []string{
+			`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Docked", "StarSystem":"Sol", "StationName":"Abraham Lincoln", "MarketID":128016640 }`,
+			`{ "timestamp":"2025-01-01T12:05:00Z", "event":"FSDJump", "StarSystem":"Alpha Centauri", "SystemAddress":1458309141194, "StarPos":[3.03125,-0.09375,3.15625], "FuelLevel":7.5 }`,
+		}, func(s State) bool {
+			return s.StarSystem == "Alpha Centauri" && s.SystemAddress == 1458309141194 &&
+				s.StarPos == [3]float64{3.03125, -0.09375, 3.15625} && !s.Docked &&
+				s.Station == "" && s.Supercruise && s.FuelMain == 7.5 &&
+				s.Timestamp == "2025-01-01T12:05:00Z"
+		}},
+		{"ship swap", []string{
+			`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Loadout", "Ship":"cobramkiii", "ShipID":2, "ShipName":"Tin Can", "ShipIdent":"TC-01", "HullHealth":0.5, "CargoCapacity":16, "FuelCapacity":{ "Main":16, "Reserve":0.49 }, "Modules":[ { "Slot":"MainEngines", "Item":"int_engine_size4_class2", "On":true, "Priority":0, "Health":1 } ] }`,
+			`{ "timestamp":"2025-01-01T12:01:00Z", "event":"ShipyardSwap", "ShipType":"sidewinder", "ShipID":1, "StoreOldShip":"CobraMkIII", "StoreShipID":2, "MarketID":128016640 }`,
+		}, func(s State) bool {
+			return s.Ship == "sidewinder" && s.ShipID == 1 && s.ShipName == "" &&
+				s.ShipIdent == "" && s.Modules == nil
+		}},
+		{"loadout", []string{
+			`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Loadout", "Ship":"cobramkiii", "ShipID":2, "ShipName":"Tin Can", "ShipIdent":"TC-01", "HullHealth":0.5, "CargoCapacity":16, "FuelCapacity":{ "Main":16, "Reserve":0.49 }, "Modules":[ { "Slot":"MainEngines", "Item":"int_engine_size4_class2", "On":true, "Priority":0, "Health":1 } ] }`,
+		}, func(s State) bool {
+			return s.Ship == "cobramkiii" && s.ShipName == "Tin Can" && s.Hull == 0.5 &&
+				s.CargoCapacity == 16 && s.FuelCapacity == 16 &&
+				reflect.DeepEqual(s.Modules, []LoadoutModule{{"MainEngines", "int_engine_size4_class2", true, 0, 1}})
+		}},
+		{"status flags", []string{
+			`status { "timestamp":"2025-01-01T12:00:00Z", "event":"Status", "Flags":16, "Fuel":{ "FuelMain":12, "FuelReservoir":0.4 }, "Cargo":3, "LegalState":"Clean" }`,
+		}, func(s State) bool {
+			return s.Supercruise && !s.Docked && s.FuelMain == 12 && s.FuelReservoir == 0.4 &&
+				s.Cargo == 3 && s.LegalState == "Clean"
+		}},
+		{"status after journal", []string{
+			`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Docked", "StarSystem":"Sol", "StationName":"Abraham Lincoln", "MarketID":128016640 }`,
+			`status { "timestamp":"2025-01-01T12:00:05Z", "event":"Status", "Flags":0 }`,
+		}, func(s State) bool {
+			return !s.Docked && s.Timestamp == "2025-01-01T12:00:05Z"
+		}},
+		{"stale status", []string{
+			`{ "timestamp":"2025-01-01T12:00:05Z", "event":"Docked", "StarSystem":"Sol", "StationName":"Abraham Lincoln", "MarketID":128016640 }`,
+			`status { "timestamp":"2025-01-01T12:00:00Z", "event":"Status", "Flags":16 }`,
+		}, func(s State) bool {
+			return s.Docked && !s.Supercruise && s.Timestamp == "2025-01-01T12:00:05Z"
+		}},
+		{"older journal event", []string{
+			`{ "timestamp":"2025-01-01T12:05:00Z", "event":"Docked", "StarSystem":"Sol", "StationName":"Abraham Lincoln", "MarketID":128016640 }`,
+			`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Commander", "Name":"Jameson", "FID":"F1" }`,
+		}, func(s State) bool {
+			// Journal events are applied in order, but do not move the
+			// state back in time.
+			return s.Docked && s.Commander == "Jameson" && s.Timestamp == "2025-01-01T12:05:00Z"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := NewGameState()
+			for _, line := range tt.events {
+				if strings.HasPrefix(line, "status ") {
+					gs.Update(statusEvent(t, strings.TrimPrefix(line, "status ")))
+				} else {
+					gs.Update(journalEvent(t, line))
+				}
+			}
+			if s := gs.Snapshot(); !tt.check(s) {
+				t.Errorf("state %+v", s)
+			}
+		})
+	}
+}
+
+func TestGameStateRawEvent(t *testing.T) {
+	gs := NewGameState()
+	c := make(chan State, 1)
+	gs.Notify(c)
+	raw, err := ParseRawEvent([]byte(`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Docked", "StarSystem":"Sol", "StationName":"Abraham Lincoln", "MarketID":128016640 }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gs.Update(raw) {
+		t.Fatal("Docked did not change the state")
+	}
+	if s := <-c; !s.Docked {
+		t.Errorf("notified %+v", s)
+	}
+	if gs.Update(raw) {
+		t.Error("Docked twice changed the state")
+	}
+	raw, _ = ParseRawEvent([]byte(`{ "timestamp":"2025-01-01T12:00:01Z", "event":"Music", "MusicTrack":"Exploration" }`))
+	if gs.Update(raw) {
+		t.Error("Music changed the state")
+	}
+}
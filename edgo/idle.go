@@ -0,0 +1,105 @@
+package edgo
+
+import (
+	"sort"
+	"time"
+)
+
+// IdleEvent is sent by EliteWatcher as GameIdle when the game has
+// written nothing for one of its IdleThresholds, and as GameResumed when
+// the game writes again after a GameIdle.
+type IdleEvent struct {
+	Base
+	Level   int   `json:"Level,omitempty"` // the threshold passed, counting from 1; 0 for GameResumed
+	Seconds int64 `json:"Seconds"`         // time since the last write
+}
+
+func (e *IdleEvent) fields() (Json, error) {
+	return Json{
+		"timestamp": e.Timestamp,
+		"event":     e.Event,
+		"Level":     e.Level,
+		"Seconds":   e.Seconds,
+	}, nil
+}
+
+// idleTracker times the writes to the journal directory.
+type idleTracker struct {
+	thresholds []time.Duration // sorted
+	last       time.Time       // time of the last write
+	level      int             // thresholds passed since then
+	timer      *time.Timer     // fires at the next threshold
+}
+
+func newIdleTracker(thresholds []time.Duration) *idleTracker {
+	t := &idleTracker{last: time.Now()}
+	for _, d := range thresholds {
+		if d > 0 {
+			t.thresholds = append(t.thresholds, d)
+		}
+	}
+	sort.Slice(t.thresholds, func(i, j int) bool { return t.thresholds[i] < t.thresholds[j] })
+	t.arm()
+	return t
+}
+
+// C fires when the next threshold passes; it is nil if there is none.
+func (t *idleTracker) C() <-chan time.Time {
+	if t.timer == nil {
+		return nil
+	}
+	return t.timer.C
+}
+
+func (t *idleTracker) arm() {
+	if t.level >= len(t.thresholds) {
+		return
+	}
+	d := t.thresholds[t.level] - time.Since(t.last)
+	if d < 0 {
+		d = 0
+	}
+	if t.timer == nil {
+		t.timer = time.NewTimer(d)
+	} else {
+		t.timer.Reset(d)
+	}
+}
+
+func (t *idleTracker) stop() {
+	if t.timer != nil && !t.timer.Stop() {
+		select {
+		case <-t.timer.C:
+		default:
+		}
+	}
+}
+
+// expired returns the GameIdle event for the threshold C fired for.
+func (t *idleTracker) expired() *IdleEvent {
+	t.level++
+	e := t.event("GameIdle", t.level)
+	t.arm()
+	return e
+}
+
+// activity records a write, and returns a GameResumed event if the game
+// had been idle.
+func (t *idleTracker) activity() *IdleEvent {
+	var e *IdleEvent
+	if t.level > 0 {
+		e = t.event("GameResumed", 0)
+	}
+	t.stop()
+	t.last = time.Now()
+	t.level = 0
+	t.arm()
+	return e
+}
+
+func (t *idleTracker) event(name string, level int) *IdleEvent {
+	e := &IdleEvent{Level: level, Seconds: int64(time.Since(t.last) / time.Second)}
+	e.Timestamp = JournalTime(time.Now())
+	e.Event = name
+	return e
+}
@@ -0,0 +1,221 @@
+package edgo
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EliteAppID is the Steam app id of Elite Dangerous, which names its
+// Proton prefix.
+const EliteAppID = "359320"
+
+// journalSubdir is the journal directory within a Windows user profile.
+var journalSubdir = filepath.Join("Saved Games", "Frontier Developments", "Elite Dangerous")
+
+// JournalDir is a directory that may hold the game's journals.
+type JournalDir struct {
+	Path          string
+	Source        string    // windows, steam, lutris, heroic or wine
+	LatestJournal string    // name of the newest journal, if any
+	Latest        time.Time // modification time of LatestJournal
+}
+
+// FindJournalDirs returns the journal directories that exist, looking in
+// the Windows profile and in the Wine prefixes of Steam (Proton), Lutris
+// and Heroic. The directory with the most recently written journal is
+// first.
+func FindJournalDirs() []JournalDir {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	var candidates []JournalDir
+	add := func(source string, patterns ...string) {
+		for _, pattern := range patterns {
+			matches, _ := filepath.Glob(pattern)
+			for _, m := range matches {
+				candidates = append(candidates, JournalDir{Path: m, Source: source})
+			}
+		}
+	}
+	prefixDirs := func(prefix string) string {
+		return filepath.Join(prefix, "drive_c", "users", "*", journalSubdir)
+	}
+
+	add("windows", filepath.Join(home, journalSubdir))
+	for _, library := range steamLibraries(home) {
+		add("steam", filepath.Join(library, "steamapps", "compatdata", EliteAppID, "pfx",
+			"drive_c", "users", "steamuser", journalSubdir))
+	}
+	add("lutris", prefixDirs(filepath.Join(home, "Games", "*")))
+	add("heroic",
+		prefixDirs(filepath.Join(home, "Games", "Heroic", "Prefixes", "*")),
+		prefixDirs(filepath.Join(home, "Games", "Heroic", "Prefixes", "*", "pfx")))
+	add("wine", prefixDirs(filepath.Join(home, ".wine")))
+
+	// Steam roots are often symlinks to one another.
+	var dirs []JournalDir
+	seen := make(map[string]bool)
+	for _, d := range candidates {
+		resolved, err := filepath.EvalSymlinks(d.Path)
+		if err != nil || seen[resolved] {
+			continue
+		}
+		if fi, err := os.Stat(resolved); err != nil || !fi.IsDir() {
+			continue
+		}
+		seen[resolved] = true
+		d.LatestJournal, d.Latest = latestJournal(d.Path)
+		dirs = append(dirs, d)
+	}
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return dirs[i].Latest.After(dirs[j].Latest)
+	})
+	return dirs
+}
+
+// DefaultJournalDir returns the journal directory with the most recent
+// journal, or the Windows directory if none is found.
+func DefaultJournalDir() string {
+	return PickJournalDir(FindJournalDirs())
+}
+
+// PickJournalDir is like DefaultJournalDir, but chooses from dirs as
+// returned by FindJournalDirs rather than looking again.
+func PickJournalDir(dirs []JournalDir) string {
+	if len(dirs) > 0 {
+		return dirs[0].Path
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, journalSubdir)
+	}
+	return ""
+}
+
+// latestJournal returns the newest journal in dir.
+func latestJournal(dir string) (string, time.Time) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", time.Time{}
+	}
+	var name string
+	var latest time.Time
+	for _, f := range files {
+		if journalRE.MatchString(strings.ToLower(f.Name())) && f.ModTime().After(latest) {
+			name, latest = f.Name(), f.ModTime()
+		}
+	}
+	return name, latest
+}
+
+// steamLibraries returns the Steam library folders, from the
+// libraryfolders.vdf of each Steam installation.
+func steamLibraries(home string) []string {
+	roots := []string{
+		filepath.Join(home, ".steam", "steam"),
+		filepath.Join(home, ".steam", "root"),
+		filepath.Join(home, ".local", "share", "Steam"),
+		filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam"),
+		filepath.Join(home, "snap", "steam", "common", ".local", "share", "Steam"),
+	}
+	libraries := append([]string(nil), roots...)
+	for _, root := range roots {
+		for _, vdf := range []string{
+			filepath.Join(root, "steamapps", "libraryfolders.vdf"),
+			filepath.Join(root, "config", "libraryfolders.vdf"),
+		} {
+			f, err := os.Open(vdf)
+			if err != nil {
+				continue
+			}
+			paths, _ := ParseLibraryFolders(f)
+			f.Close()
+			libraries = append(libraries, paths...)
+		}
+	}
+	return libraries
+}
+
+// ParseLibraryFolders returns the library paths in a Steam
+// libraryfolders.vdf file. Both the current format, where each library
+// is an object with a "path" key, and the older one, where numbered keys
+// map directly to paths, are understood.
+func ParseLibraryFolders(r io.Reader) ([]string, error) {
+	tokens, err := vdfTokens(r)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "{":
+			depth++
+			continue
+		case "}":
+			depth--
+			continue
+		}
+		if i+1 >= len(tokens) || tokens[i+1] == "{" || tokens[i+1] == "}" {
+			continue
+		}
+		key, value := tokens[i][1:], tokens[i+1][1:]
+		i++
+		if key == "path" {
+			paths = append(paths, value)
+		} else if _, err := strconv.Atoi(key); err == nil && depth == 1 {
+			paths = append(paths, value)
+		}
+	}
+	return paths, nil
+}
+
+// vdfTokens splits a VDF file into braces and strings. Strings are
+// returned with a leading quote, so that they cannot be mistaken for
+// braces.
+func vdfTokens(r io.Reader) ([]string, error) {
+	var tokens []string
+	reader := bufio.NewReader(r)
+	for {
+		c, err := reader.ReadByte()
+		if err == io.EOF {
+			return tokens, nil
+		} else if err != nil {
+			return nil, err
+		}
+		switch c {
+		case '{', '}':
+			tokens = append(tokens, string(c))
+		case '"':
+			var b strings.Builder
+			b.WriteByte('"')
+			for {
+				c, err := reader.ReadByte()
+				if err != nil {
+					return nil, io.ErrUnexpectedEOF
+				}
+				if c == '"' {
+					break
+				}
+				if c == '\\' {
+					if c, err = reader.ReadByte(); err != nil {
+						return nil, io.ErrUnexpectedEOF
+					}
+				}
+				b.WriteByte(c)
+			}
+			tokens = append(tokens, b.String())
+		case '/':
+			// A // comment runs to the end of the line.
+			reader.ReadString('\n')
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package edgo
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPickJournalDir(t *testing.T) {
+	dirs := []JournalDir{{Path: "/newest"}, {Path: "/older"}}
+	if got := PickJournalDir(dirs); got != "/newest" {
+		t.Errorf("PickJournalDir = %q, want /newest", got)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	if got, want := PickJournalDir(nil), filepath.Join(home, journalSubdir); got != want {
+		t.Errorf("PickJournalDir(nil) = %q, want %q", got, want)
+	}
+}
+
+func TestParseLibraryFolders(t *testing.T) {
+	tests := []struct {
+		name string
+		vdf  string
+		want []string
+	}{
+		{"current", `"libraryfolders"
+{
+	"contentstatsid"		"-4462101434316937343"
+	"0"
+	{
+		"path"		"/home/jameson/.local/share/Steam"
+		"label"		""
+		"contentid"		"1427327414556231563"
+		"totalsize"		"0"
+		"apps"
+		{
+			"228980"		"180193870"
+			"359320"		"68722532891"
+		}
+	}
+	"1"
+	{
+		"path"		"/mnt/games/SteamLibrary"
+		"label"		"games"
+		"apps"
+		{
+		}
+	}
+}
+`, []string{"/home/jameson/.local/share/Steam", "/mnt/games/SteamLibrary"}},
+		{"legacy", `"LibraryFolders"
+{
+	"TimeNextStatsReport"		"1619540435"
+	"ContentStatsID"		"-4462101434316937343"
+	"1"		"/mnt/games/SteamLibrary"
+	"2"		"/mnt/more games"
+}
+`, []string{"/mnt/games/SteamLibrary", "/mnt/more games"}},
+		{"escaped", `// Written by Steam on Windows
+"libraryfolders"
+{
+	"0"
+	{
+		"path"		"C:\\Program Files (x86)\\Steam"
+	}
+	"1"
+	{
+		"path"		"D:\\Games \"Steam\"\\Library"
+	}
+}
+`, []string{`C:\Program Files (x86)\Steam`, `D:\Games "Steam"\Library`}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		got, err := ParseLibraryFolders(strings.NewReader(tt.vdf))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: paths %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := ParseLibraryFolders(strings.NewReader(`"libraryfolders" { "0" { "path" "/mnt`)); err != io.ErrUnexpectedEOF {
+		t.Errorf("unterminated string: %v", err)
+	}
+}
+
+func TestFindJournalDirsProton(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	// Elite is installed in a second library, listed by the Steam
+	// installation in the home directory.
+	library := filepath.Join(home, "games", "SteamLibrary")
+	steamapps := filepath.Join(home, ".local", "share", "Steam", "steamapps")
+	if err := os.MkdirAll(steamapps, 0755); err != nil {
+		t.Fatal(err)
+	}
+	vdf := `"libraryfolders" { "0" { "path" "` + library + `" "apps" { "359320" "1" } } }`
+	if err := ioutil.WriteFile(filepath.Join(steamapps, "libraryfolders.vdf"), []byte(vdf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(library, "steamapps", "compatdata", EliteAppID, "pfx",
+		"drive_c", "users", "steamuser", journalSubdir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeJournal(t, dir, "Journal.2025-01-01T120000.01.log", testEvent(1))
+
+	dirs := FindJournalDirs()
+	if len(dirs) != 1 || dirs[0].Path != dir || dirs[0].Source != "steam" ||
+		dirs[0].LatestJournal != "Journal.2025-01-01T120000.01.log" {
+		t.Fatalf("FindJournalDirs = %+v", dirs)
+	}
+	if got := DefaultJournalDir(); got != dir {
+		t.Errorf("DefaultJournalDir = %q, want %q", got, dir)
+	}
+}
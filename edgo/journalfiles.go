@@ -0,0 +1,194 @@
+package edgo
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JournalSource is a journal file that may be stored as-is, gzipped,
+// or inside a zip archive.
+type JournalSource struct {
+	Name  string // journal file name, e.g. Journal.2025-01-01T120000.01.log
+	Path  string // file on disk; the archive for zip entries
+	Entry string // entry name within the zip archive, if any
+}
+
+func (s JournalSource) String() string {
+	if s.Entry != "" {
+		return s.Path + "!" + s.Entry
+	}
+	return s.Path
+}
+
+// Open returns the uncompressed journal contents.
+func (s JournalSource) Open() (io.ReadCloser, error) {
+	if s.Entry != "" {
+		archive, err := zip.OpenReader(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range archive.File {
+			if f.Name == s.Entry {
+				r, err := f.Open()
+				if err != nil {
+					archive.Close()
+					return nil, err
+				}
+				return &multiCloser{r, []io.Closer{r, archive}}, nil
+			}
+		}
+		archive.Close()
+		return nil, os.ErrNotExist
+	}
+
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(strings.ToLower(s.Path), ".gz") {
+		return f, nil
+	}
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &multiCloser{r, []io.Closer{r, f}}, nil
+}
+
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiCloser) Close() error {
+	var err error
+	for _, c := range m.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// JournalNameTime returns the time a journal was started from its file
+// name, in either the legacy Journal.YYMMDDhhmmss.NN.log format or the
+// current Journal.YYYY-MM-DDThhmmss.NN.log one, and its part number.
+func JournalNameTime(name string) (t time.Time, part int, ok bool) {
+	f := strings.Split(filepath.Base(name), ".")
+	if len(f) < 4 || !strings.EqualFold(f[0], "journal") {
+		return time.Time{}, 0, false
+	}
+	part, err := strconv.Atoi(f[2])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	for _, layout := range []string{"2006-01-02T150405", "060102150405"} {
+		if t, err := time.Parse(layout, strings.ToUpper(f[1])); err == nil {
+			return t, part, true
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+// lessJournalName orders journal file names by the time in the name,
+// then by part. Names without a time sort first, by name.
+func lessJournalName(a, b string) bool {
+	ta, pa, oka := JournalNameTime(a)
+	tb, pb, okb := JournalNameTime(b)
+	switch {
+	case oka != okb:
+		return okb
+	case !ta.Equal(tb):
+		return ta.Before(tb)
+	case pa != pb:
+		return pa < pb
+	}
+	return strings.ToLower(filepath.Base(a)) < strings.ToLower(filepath.Base(b))
+}
+
+// FindJournals expands paths into journal sources sorted by the time in
+// the journal name. Each path may be a .log or .log.gz journal, a .zip archive of
+// journals, or a directory containing any of those.
+func FindJournals(paths ...string) ([]JournalSource, error) {
+	var sources []JournalSource
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			found, err := journalSources(p, true)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, found...)
+			continue
+		}
+
+		files, err := ioutil.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			found, err := journalSources(filepath.Join(p, file.Name()), false)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, found...)
+		}
+	}
+
+	sort.SliceStable(sources, func(i, j int) bool {
+		return lessJournalName(sources[i].Name, sources[j].Name)
+	})
+	return sources, nil
+}
+
+// journalSources returns the journals in filename. Unless explicit is
+// set, plain and gzipped files must be named like journals.
+func journalSources(filename string, explicit bool) ([]JournalSource, error) {
+	base := filepath.Base(filename)
+	lower := strings.ToLower(base)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		archive, err := zip.OpenReader(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer archive.Close()
+
+		var sources []JournalSource
+		for _, f := range archive.File {
+			name := path.Base(f.Name)
+			if journalRE.MatchString(strings.ToLower(name)) {
+				sources = append(sources, JournalSource{Name: name, Path: filename, Entry: f.Name})
+			}
+		}
+		return sources, nil
+
+	case strings.HasSuffix(lower, ".gz"):
+		name := base[:len(base)-len(".gz")]
+		if explicit || journalRE.MatchString(strings.ToLower(name)) {
+			return []JournalSource{{Name: name, Path: filename}}, nil
+		}
+
+	default:
+		if explicit || journalRE.MatchString(lower) {
+			return []JournalSource{{Name: base, Path: filename}}, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,147 @@
+package edgo
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func journalContent(name string) string {
+	return fmt.Sprintf(`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Fileheader", "part":1 }`+"\n"+
+		`{ "timestamp":"2025-01-01T12:00:01Z", "event":"Test", "Name":%q }`+"\n", name)
+}
+
+func writeGzipJournal(t *testing.T, filename string) {
+	t.Helper()
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := gzip.NewWriter(f)
+	name := strings.TrimSuffix(filepath.Base(filename), ".gz")
+	if _, err := w.Write([]byte(journalContent(name))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeZipJournals writes an archive holding entries, named like
+// journals or not, in the order given.
+func writeZipJournals(t *testing.T, filename string, entries ...string) {
+	t.Helper()
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for _, entry := range entries {
+		ew, err := w.Create(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ew.Write([]byte(journalContent(filepath.Base(entry))))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBulkReaderArchives(t *testing.T) {
+	dir := t.TempDir()
+	writeGzipJournal(t, filepath.Join(dir, "Journal.2025-01-03T120000.01.log.gz"))
+	writeGzipJournal(t, filepath.Join(dir, "notes.txt.gz"))
+	writeZipJournals(t, filepath.Join(dir, "backup.zip"),
+		"journals/Journal.2025-01-04T120000.01.log",
+		"journals/Journal.2025-01-01T120000.01.log",
+		"journals/readme.txt",
+		"Journal.241231120000.01.log",
+		"Journal.2025-01-02T120000.02.log",
+		"Journal.2025-01-02T120000.01.log")
+	writeJournal(t, dir, "Journal.2025-01-05T120000.01.log",
+		`{ "timestamp":"2025-01-05T12:00:00Z", "event":"Test", "Name":"Journal.2025-01-05T120000.01.log" }`)
+
+	want := []string{
+		"Journal.241231120000.01.log",
+		"Journal.2025-01-01T120000.01.log",
+		"Journal.2025-01-02T120000.01.log",
+		"Journal.2025-01-02T120000.02.log",
+		"Journal.2025-01-03T120000.01.log",
+		"Journal.2025-01-04T120000.01.log",
+		"Journal.2025-01-05T120000.01.log",
+	}
+	var got []string
+	bulk := &BulkReader{Files: []string{dir}, Workers: 3}
+	if err := bulk.Run(func(e *BulkEvent) error {
+		if GetEventName(e.Event) != "Test" {
+			return nil
+		}
+		// Each journal holds its own name, so that the contents can be
+		// told apart from the name they are delivered under.
+		if name, _ := e.Event.String("Name"); name != e.File {
+			t.Errorf("%s holds the contents of %s", e.File, name)
+		}
+		got = append(got, e.File)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("files read\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestJournalSources(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "journals.zip")
+	writeZipJournals(t, archive, "a/Journal.2025-01-02T120000.01.log", "Journal.2025-01-01T120000.01.log")
+	gz := filepath.Join(dir, "renamed.gz")
+	writeGzipJournal(t, gz)
+
+	// An archive given explicitly, and a gzipped file that would be
+	// skipped in a directory.
+	sources, err := FindJournals(archive, gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range sources {
+		got = append(got, s.String())
+	}
+	// Names without a time sort first.
+	want := []string{
+		gz,
+		archive + "!Journal.2025-01-01T120000.01.log",
+		archive + "!a/Journal.2025-01-02T120000.01.log",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("sources\n%v\nwant\n%v", got, want)
+	}
+
+	for _, s := range sources {
+		r, err := s.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, 64)
+		n, _ := r.Read(b)
+		if err := r.Close(); err != nil {
+			t.Error(err)
+		}
+		if !strings.HasPrefix(string(b[:n]), `{ "timestamp"`) {
+			t.Errorf("%s starts with %q", s, b[:n])
+		}
+	}
+
+	missing := JournalSource{Name: "Journal.2025-01-03T120000.01.log", Path: archive, Entry: "Journal.2025-01-03T120000.01.log"}
+	if _, err := missing.Open(); !os.IsNotExist(err) {
+		t.Errorf("Open of a missing entry: %v", err)
+	}
+}
@@ -0,0 +1,351 @@
+package edgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// JournalTimeFormat is the layout of event timestamps.
+const JournalTimeFormat = "2006-01-02T15:04:05Z"
+
+// JournalTime formats t as an event timestamp.
+func JournalTime(t time.Time) string {
+	return t.UTC().Format(JournalTimeFormat)
+}
+
+// JournalName returns the name the game gives the journal for a session
+// started at t; part counts from 1 as the game starts new files.
+func JournalName(t time.Time, part int) string {
+	return fmt.Sprintf("Journal.%s.%02d.log", t.Format("2006-01-02T150405"), part)
+}
+
+// Field is a named value of an event.
+type Field struct {
+	Name  string
+	Value interface{}
+}
+
+// Fields is an event that keeps the order of its fields, so that it can
+// be written back as the game wrote it. Nested objects are Fields too,
+// arrays are []interface{}, and numbers are json.Number.
+type Fields []Field
+
+// ParseFields parses a journal line, keeping the order of its fields.
+func ParseFields(line []byte) (Fields, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	v, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := v.(Fields)
+	if !ok {
+		return nil, errors.New("journal line is not an object")
+	}
+	if err := decodeEnd(dec); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		f := Fields{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			f = append(f, Field{key.(string), value})
+		}
+		_, err := dec.Token()
+		return f, err
+	case json.Delim('['):
+		a := []interface{}{}
+		for dec.More() {
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, value)
+		}
+		_, err := dec.Token()
+		return a, err
+	}
+	return tok, nil
+}
+
+// Get returns the value of the named field.
+func (f Fields) Get(name string) (interface{}, bool) {
+	for _, field := range f {
+		if field.Name == name {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set replaces the value of the named field, or appends the field.
+func (f Fields) Set(name string, value interface{}) Fields {
+	for i := range f {
+		if f[i].Name == name {
+			f[i].Value = value
+			return f
+		}
+	}
+	return append(f, Field{name, value})
+}
+
+// Json converts the event to a Json, as ParseJournalLine would return.
+func (f Fields) Json() Json {
+	return Json(unorder(f).(map[string]interface{}))
+}
+
+func unorder(v interface{}) interface{} {
+	switch c := v.(type) {
+	case Fields:
+		m := make(map[string]interface{}, len(c))
+		for _, field := range c {
+			m[field.Name] = unorder(field.Value)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(c))
+		for i := range c {
+			a[i] = unorder(c[i])
+		}
+		return a
+	}
+	return v
+}
+
+func (f Fields) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range f {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// toFields converts an event to Fields with timestamp and event first.
+// Structs keep their field order; the keys of a Json are sorted.
+func toFields(v interface{}) (Fields, error) {
+	f, ok := v.(Fields)
+	if !ok {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if f, err = ParseFields(b); err != nil {
+			return nil, err
+		}
+	}
+
+	header := make(Fields, 0, len(f))
+	rest := make(Fields, 0, len(f))
+	for _, name := range []string{"timestamp", "event"} {
+		if value, ok := f.Get(name); ok {
+			header = append(header, Field{name, value})
+		}
+	}
+	for _, field := range f {
+		if field.Name != "timestamp" && field.Name != "event" {
+			rest = append(rest, field)
+		}
+	}
+	return append(header, rest...), nil
+}
+
+// MarshalJournal returns an event as the game writes it: a single line
+// like
+//
+//	{ "timestamp":"2025-01-01T12:00:00Z", "event":"FSDJump", "StarPos":[0.0,0.0,0.0] }
+//
+// ending in a newline. The event may be Fields, a Json, a *RawEvent or
+// one of the status structs.
+func MarshalJournal(v interface{}) ([]byte, error) {
+	f, err := toFields(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := writeJournalValue(&buf, f); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func writeJournalValue(buf *bytes.Buffer, v interface{}) error {
+	switch c := v.(type) {
+	case Fields:
+		if len(c) == 0 {
+			buf.WriteString("{ }")
+			return nil
+		}
+		buf.WriteString("{ ")
+		for i, field := range c {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if err := writeJournalString(buf, field.Name); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeJournalValue(buf, field.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(" }")
+
+	case []interface{}:
+		// Arrays of numbers and strings are written tight; arrays of
+		// objects are spaced like objects.
+		start, sep, end := "[", ",", "]"
+		for _, elem := range c {
+			switch elem.(type) {
+			case Fields, []interface{}:
+				start, sep, end = "[ ", ", ", " ]"
+			}
+		}
+		if len(c) == 0 {
+			start, end = "[ ", "]"
+		}
+		buf.WriteString(start)
+		for i, elem := range c {
+			if i > 0 {
+				buf.WriteString(sep)
+			}
+			if err := writeJournalValue(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(end)
+
+	case string:
+		return writeJournalString(buf, c)
+
+	case json.Number:
+		buf.WriteString(c.String())
+
+	default:
+		b, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	return nil
+}
+
+// writeJournalString writes a JSON string without the HTML escaping of
+// json.Marshal, as the game does.
+func writeJournalString(buf *bytes.Buffer, s string) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	buf.Write(bytes.TrimRight(b.Bytes(), "\n"))
+	return nil
+}
+
+// JournalWriter appends events to a journal file in the game's format.
+// Each event is written with a single write, so a watcher tailing the
+// file sees whole lines.
+type JournalWriter struct {
+	Filename string
+	Now      func() time.Time // timestamps events that have none; defaults to time.Now
+
+	f *os.File
+}
+
+// CreateJournal creates the journal for a session started at t in dir.
+func CreateJournal(dir string, t time.Time, part int) (*JournalWriter, error) {
+	filename := filepath.Join(dir, JournalName(t, part))
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &JournalWriter{Filename: filename, f: f}, nil
+}
+
+// OpenJournal opens an existing journal to append to it.
+func OpenJournal(filename string) (*JournalWriter, error) {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &JournalWriter{Filename: filename, f: f}, nil
+}
+
+// Write appends an event, adding a timestamp if it has none.
+func (w *JournalWriter) Write(v interface{}) error {
+	f, err := toFields(v)
+	if err != nil {
+		return err
+	}
+	if _, ok := f.Get("timestamp"); !ok {
+		now := time.Now
+		if w.Now != nil {
+			now = w.Now
+		}
+		f = append(Fields{{"timestamp", JournalTime(now())}}, f...)
+	}
+	b, err := MarshalJournal(f)
+	if err != nil {
+		return err
+	}
+	_, err = w.f.Write(b)
+	return err
+}
+
+func (w *JournalWriter) Close() error {
+	return w.f.Close()
+}
+
+// WriteStatusFile writes a status event to its registered file in dir,
+// replacing the file's contents in place as the game does.
+func WriteStatusFile(dir string, v interface{}) error {
+	b, err := MarshalJournal(v)
+	if err != nil {
+		return err
+	}
+	event := GetEventNameByte(b)
+	sf, ok := LookupStatusEvent(event)
+	if !ok {
+		return fmt.Errorf("%q is not a status file event", event)
+	}
+	return ioutil.WriteFile(filepath.Join(dir, sf.Filename), b, 0644)
+}
@@ -0,0 +1,141 @@
+package edgo
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// Json is an untyped journal entry. Numbers are decoded as json.Number
+// so that 64-bit identifiers such as SystemAddress, MarketID and
+// MissionID keep their full precision.
+type Json map[string]interface{}
+
+// Lookup returns the value found by following path through nested
+// objects and arrays. Array elements are addressed by their decimal
+// index, so Lookup("Route", "0", "StarSystem") returns the first
+// system of a nav route.
+func (j Json) Lookup(path ...string) (interface{}, bool) {
+	var v interface{} = map[string]interface{}(j)
+	for _, key := range path {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = c[key]; !ok {
+				return nil, false
+			}
+		case Json:
+			var ok bool
+			if v, ok = c[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(c) {
+				return nil, false
+			}
+			v = c[idx]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// String returns the string found at path.
+func (j Json) String(path ...string) (string, bool) {
+	v, ok := j.Lookup(path...)
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+// Int64 returns the integer found at path. Floating point values are
+// only accepted when they have no fractional part.
+func (j Json) Int64(path ...string) (int64, bool) {
+	v, ok := j.Lookup(path...)
+	if !ok {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+		if f, err := n.Float64(); err == nil {
+			return floatInt64(f)
+		}
+	case float64:
+		return floatInt64(n)
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+// floatInt64 converts f to an int64 if it is a whole number in range.
+func floatInt64(f float64) (int64, bool) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// Float returns the number found at path.
+func (j Json) Float(path ...string) (float64, bool) {
+	v, ok := j.Lookup(path...)
+	if !ok {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// Bool returns the boolean found at path.
+func (j Json) Bool(path ...string) (bool, bool) {
+	v, ok := j.Lookup(path...)
+	if !ok {
+		return false, false
+	}
+	b, ok := v.(bool)
+	return b, ok
+}
+
+// Object returns the nested object found at path.
+func (j Json) Object(path ...string) (Json, bool) {
+	v, ok := j.Lookup(path...)
+	if !ok {
+		return nil, false
+	}
+	switch o := v.(type) {
+	case map[string]interface{}:
+		return Json(o), true
+	case Json:
+		return o, true
+	}
+	return nil, false
+}
+
+// Array returns the array found at path.
+func (j Json) Array(path ...string) ([]interface{}, bool) {
+	v, ok := j.Lookup(path...)
+	if !ok {
+		return nil, false
+	}
+	a, ok := v.([]interface{})
+	return a, ok
+}
@@ -0,0 +1,96 @@
+package edgo
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestJsonInt64(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  int64
+		ok    bool
+	}{
+		{json.Number("42"), 42, true},
+		{json.Number("-9223372036854775808"), math.MinInt64, true},
+		{json.Number("9223372036854775807"), math.MaxInt64, true},
+		{json.Number("1e3"), 1000, true},
+		{json.Number("2.0"), 2, true},
+		{json.Number("2.5"), 0, false},
+		{json.Number("9223372036854775808"), 0, false},
+		{json.Number("1e19"), 0, false},
+		{json.Number("-1e19"), 0, false},
+		{json.Number("1e400"), 0, false},
+		{json.Number("abc"), 0, false},
+		{float64(7), 7, true},
+		{-0.5, 0, false},
+		{float64(1 << 63), 0, false},
+		{float64(-1 << 63), math.MinInt64, true},
+		{math.Inf(1), 0, false},
+		{math.NaN(), 0, false},
+		{int(3), 3, true},
+		{int64(4), 4, true},
+		{"5", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := Json{"n": tt.value}.Int64("n")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Int64(%#v) = %d, %v, want %d, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseJournalLine(t *testing.T) {
+	tests := []struct {
+		line string
+		ok   bool
+	}{
+		{`{ "event":"Music" }`, true},
+		{`{ "event":"Music" }` + "\r\n", true},
+		{`{ "event":"Music" } x`, false},
+		{`{ "event":"Music" }}`, false},
+		{`{ "event":"Music" }{ "event":"Music" }`, false},
+		{`{ "event":"Music" } 1`, false},
+		{`{ "event":"Music"`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		_, err := ParseJournalLine([]byte(tt.line))
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseJournalLine(%q): %v", tt.line, err)
+		}
+		_, err = ParseFields([]byte(tt.line))
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseFields(%q): %v", tt.line, err)
+		}
+	}
+}
+
+func TestParseJournalLinePrecision(t *testing.T) {
+	// Above 2^53, where a float64 can no longer hold every integer.
+	const address = 9007199254740993
+	line := []byte(`{ "timestamp":"2025-01-01T12:00:00Z", "event":"FSDJump", "SystemAddress":9007199254740993, "StarPos":[0.03125,-1e3,0] }`)
+	j, err := ParseJournalLine(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := j.Int64("SystemAddress"); !ok || n != address {
+		t.Errorf("SystemAddress = %d, %v, want %d", n, ok, address)
+	}
+	if n, ok := j["SystemAddress"].(json.Number); !ok || n.String() != "9007199254740993" {
+		t.Errorf("SystemAddress = %#v, want the number as written", j["SystemAddress"])
+	}
+	if f, ok := j.Float("StarPos", "1"); !ok || f != -1000 {
+		t.Errorf("StarPos[1] = %v, %v, want -1000", f, ok)
+	}
+
+	out, err := MarshalJournal(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"SystemAddress":9007199254740993 `) {
+		t.Errorf("written back as %s", out)
+	}
+}
@@ -4,73 +4,63 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"io"
 	"io/ioutil"
-	"path/filepath"
-	"strings"
 )
 
-type Json map[string]interface{}
+var ErrTrailingData = errors.New("journal line has data after its object")
 
+// ParseJournalLine decodes a journal line, which must hold a single JSON
+// object and nothing after it but white space.
 func ParseJournalLine(contents []byte) (Json, error) {
 	jsonMap := make(Json)
-	err := json.Unmarshal(contents, &jsonMap)
-	return jsonMap, err
+	dec := json.NewDecoder(bytes.NewReader(contents))
+	dec.UseNumber()
+	if err := dec.Decode(&jsonMap); err != nil {
+		return jsonMap, err
+	}
+	return jsonMap, decodeEnd(dec)
 }
 
-func IsStatusFile(filename string) bool {
-	base := strings.ToLower(filepath.Base(filename))
-	switch base {
-	case "cargo.json":
-		return true
-	case "market.json":
-		return true
-	case "modulesinfo.json":
-		return true
-	case "navroute.json":
-		return true
-	case "outfitting.json":
-		return true
-	case "shipyard.json":
-		return true
-	case "status.json":
-		return true
-	case "shiplocker.json":
-		return true
-	default:
-		return false
+// decodeEnd returns ErrTrailingData unless dec has nothing left to read
+// but white space. A Decoder reads one value at a time, so it does not
+// notice what follows the value on its own.
+func decodeEnd(dec *json.Decoder) error {
+	if _, err := dec.Token(); err != io.EOF {
+		return ErrTrailingData
 	}
+	return nil
+}
+
+func IsStatusFile(filename string) bool {
+	_, ok := LookupStatusFile(filename)
+	return ok
 }
 
 func GetStatusInterface(filename string) interface{} {
-	base := strings.ToLower(filepath.Base(filename))
-	switch base {
-	case "cargo.json":
-		return &Cargo{}
-	case "market.json":
-		return &Market{}
-	case "modulesinfo.json":
-		return &ModulesInfo{}
-	case "navroute.json":
-		return &NavRoute{}
-	case "outfitting.json":
-		return &Outfitting{}
-	case "shipyard.json":
-		return &Shipyard{}
-	case "status.json":
-		return &Status{}
-	case "shiplocker.json":
-		return make(map[string]interface{}) // TODO
-	default:
-		return make(map[string]interface{})
+	if sf, ok := LookupStatusFile(filename); ok && sf.New != nil {
+		return sf.New()
 	}
+	return make(Json)
 }
 
 func ParseStatusContents(filename string, content []byte) (interface{}, error) {
-	if !IsStatusFile(filename) {
+	sf, ok := LookupStatusFile(filename)
+	if !ok {
 		return nil, errors.New("Not a status file")
 	}
-	obj := GetStatusInterface(filename)
+	if sf.New == nil {
+		obj, err := ParseJournalLine(content)
+		if _, ok := obj["event"]; !ok && err == nil {
+			obj["event"] = sf.Event
+		}
+		return obj, err
+	}
+	obj := sf.New()
 	err := json.Unmarshal(content, obj)
+	if b, ok := obj.(eventBase); ok && b.EventBase().Event == "" {
+		b.EventBase().Event = sf.Event
+	}
 	return obj, err
 }
 
@@ -82,19 +72,15 @@ func ParseStatusData(filename string) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	obj := GetStatusInterface(filename)
-	err = json.Unmarshal(content, obj)
-	return obj, err
+	return ParseStatusContents(filename, content)
 }
 
 func GetEventNameByte(contents []byte) string {
-	// The quick and dirty way is to find the event field and extract
-	// the value from it.
-	idx := bytes.Index(contents, []byte(`"event"`))
-	if idx != -1 {
-		tmp := contents[idx+7 : len(contents)-1]
-		f := bytes.SplitN(tmp, []byte(`"`), 3)
-		return string(f[1])
+	if event, _, ok := ScanEventHeader(contents); ok {
+		return string(event)
+	}
+	if content, err := ParseJournalLine(contents); err == nil {
+		return GetEventName(content)
 	}
 	return ""
 }
@@ -106,36 +92,25 @@ func GetEventName(i interface{}) string {
 	case string:
 		return GetEventNameByte([]byte(v))
 	case Json:
-		return v["event"].(string)
-	case *Cargo:
-		return v.Event
-	case *Market:
-		return v.Event
-	case *ModulesInfo:
-		return v.Event
-	case *NavRoute:
-		return v.Event
-	case *Outfitting:
-		return v.Event
-	case *Shipyard:
-		return v.Event
-	case *Status:
-		return v.Event
-	case *Base:
+		name, _ := v.String("event")
+		return name
+	case *RawEvent:
 		return v.Event
+	case *SourcedEvent:
+		return GetEventName(v.Event)
+	case eventBase:
+		return v.EventBase().Event
 	default:
 		return ""
 	}
 }
 
 func GetEventTimestampByte(contents []byte) string {
-	// The quick and dirty way is to find the event field and extract
-	// the value from it.
-	idx := bytes.Index(contents, []byte(`"timestamp"`))
-	if idx != -1 {
-		tmp := contents[idx+11 : len(contents)-1]
-		f := bytes.SplitN(tmp, []byte(`"`), 3)
-		return string(f[1])
+	if _, timestamp, ok := ScanEventHeader(contents); ok {
+		return string(timestamp)
+	}
+	if content, err := ParseJournalLine(contents); err == nil {
+		return GetEventTimestamp(content)
 	}
 	return ""
 }
@@ -147,23 +122,14 @@ func GetEventTimestamp(i interface{}) string {
 	case string:
 		return GetEventTimestampByte([]byte(v))
 	case Json:
-		return v["timestamp"].(string)
-	case *Cargo:
-		return v.Timestamp
-	case *Market:
-		return v.Timestamp
-	case *ModulesInfo:
-		return v.Timestamp
-	case *NavRoute:
-		return v.Timestamp
-	case *Outfitting:
-		return v.Timestamp
-	case *Shipyard:
-		return v.Timestamp
-	case *Status:
-		return v.Timestamp
-	case *Base:
+		t, _ := v.String("timestamp")
+		return t
+	case *RawEvent:
 		return v.Timestamp
+	case *SourcedEvent:
+		return GetEventTimestamp(v.Event)
+	case eventBase:
+		return v.EventBase().Timestamp
 	default:
 		return ""
 	}
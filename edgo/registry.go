@@ -0,0 +1,97 @@
+package edgo
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StatusFile describes a JSON file that the game, or another tool,
+// rewrites in the journal directory.
+type StatusFile struct {
+	Filename string             // base name, e.g. "Status.json"
+	Event    string             // event name, used when the file has none
+	New      func() interface{} // returns a pointer to decode into; nil for Json
+}
+
+var (
+	ErrStatusFileRegistered = errors.New("edgo: status file already registered")
+
+	statusMu    sync.RWMutex
+	statusFiles = make(map[string]StatusFile) // guarded by statusMu, keyed by lower case filename
+)
+
+func init() {
+	for _, sf := range []StatusFile{
+		{"Backpack.json", "Backpack", func() interface{} { return &Backpack{} }},
+		{"Cargo.json", "Cargo", func() interface{} { return &Cargo{} }},
+		{"FCMaterials.json", "FCMaterials", func() interface{} { return &FCMaterials{} }},
+		{"Market.json", "Market", func() interface{} { return &Market{} }},
+		{"ModulesInfo.json", "ModuleInfo", func() interface{} { return &ModulesInfo{} }},
+		{"NavRoute.json", "NavRoute", func() interface{} { return &NavRoute{} }},
+		{"Outfitting.json", "Outfitting", func() interface{} { return &Outfitting{} }},
+		{"ShipLocker.json", "ShipLocker", func() interface{} { return &ShipLocker{} }},
+		{"Shipyard.json", "Shipyard", func() interface{} { return &Shipyard{} }},
+		{"Status.json", "Status", func() interface{} { return &Status{} }},
+	} {
+		if err := RegisterStatusFile(sf); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterStatusFile adds a status file to the registry, so that it is
+// read by EliteWatcher and parsed by ParseStatusContents.
+func RegisterStatusFile(sf StatusFile) error {
+	if sf.Filename == "" || filepath.Base(sf.Filename) != sf.Filename {
+		return errors.New("edgo: bad status file name " + sf.Filename)
+	}
+	key := strings.ToLower(sf.Filename)
+
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	if _, ok := statusFiles[key]; ok {
+		return ErrStatusFileRegistered
+	}
+	statusFiles[key] = sf
+	return nil
+}
+
+// LookupStatusFile returns the registered status file for filename.
+func LookupStatusFile(filename string) (StatusFile, bool) {
+	key := strings.ToLower(filepath.Base(filename))
+
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	sf, ok := statusFiles[key]
+	return sf, ok
+}
+
+// LookupStatusEvent returns the registered status file for an event name.
+func LookupStatusEvent(event string) (StatusFile, bool) {
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	for _, sf := range statusFiles {
+		if sf.Event == event {
+			return sf, true
+		}
+	}
+	return StatusFile{}, false
+}
+
+// StatusFiles returns the registered status files sorted by filename.
+func StatusFiles() []StatusFile {
+	statusMu.RLock()
+	result := make([]StatusFile, 0, len(statusFiles))
+	for _, sf := range statusFiles {
+		result = append(result, sf)
+	}
+	statusMu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Filename) < strings.ToLower(result[j].Filename)
+	})
+	return result
+}
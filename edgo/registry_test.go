@@ -0,0 +1,104 @@
+package edgo
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatusFileRegistry(t *testing.T) {
+	for _, tt := range []struct {
+		filename string
+		event    string
+		want     interface{}
+	}{
+		{"Status.json", "Status", &Status{}},
+		{"status.json", "Status", &Status{}},
+		{filepath.Join("journals", "CARGO.JSON"), "Cargo", &Cargo{}},
+		{"ModulesInfo.json", "ModuleInfo", &ModulesInfo{}},
+		{"ShipLocker.json", "ShipLocker", &ShipLocker{}},
+		{"FCMaterials.json", "FCMaterials", &FCMaterials{}},
+	} {
+		if !IsStatusFile(tt.filename) {
+			t.Errorf("%s is not a status file", tt.filename)
+			continue
+		}
+		sf, _ := LookupStatusFile(tt.filename)
+		if sf.Event != tt.event {
+			t.Errorf("%s: event %q, want %q", tt.filename, sf.Event, tt.event)
+		}
+		if byEvent, ok := LookupStatusEvent(tt.event); !ok || byEvent.Filename != sf.Filename {
+			t.Errorf("LookupStatusEvent(%q) = %q, %v, want %q", tt.event, byEvent.Filename, ok, sf.Filename)
+		}
+
+		// Dispatch decodes into the registered type, naming the
+		// event when the file does not.
+		v, err := ParseStatusContents(tt.filename, []byte(`{ "timestamp":"2025-01-01T12:00:00Z" }`))
+		if err != nil {
+			t.Errorf("%s: %v", tt.filename, err)
+			continue
+		}
+		if got, want := fmt.Sprintf("%T", v), fmt.Sprintf("%T", tt.want); got != want {
+			t.Errorf("%s decoded as %s, want %s", tt.filename, got, want)
+		}
+		if GetEventName(v) != tt.event {
+			t.Errorf("%s: event %q, want %q", tt.filename, GetEventName(v), tt.event)
+		}
+	}
+
+	for _, name := range []string{"Journal.2025-01-01T120000.01.log", "Status.json.bak", "Unknown.json"} {
+		if IsStatusFile(name) {
+			t.Errorf("%s is a status file", name)
+		}
+		if _, err := ParseStatusContents(name, []byte(`{}`)); err == nil {
+			t.Errorf("%s was parsed", name)
+		}
+	}
+	if _, ok := LookupStatusEvent("FSDJump"); ok {
+		t.Error("FSDJump has a status file")
+	}
+
+	files := StatusFiles()
+	if len(files) != 10 {
+		t.Errorf("%d status files registered, want 10", len(files))
+	}
+	for i := 1; i < len(files); i++ {
+		if files[i-1].Filename > files[i].Filename {
+			t.Errorf("StatusFiles not sorted: %s before %s", files[i-1].Filename, files[i].Filename)
+		}
+	}
+}
+
+func TestRegisterStatusFile(t *testing.T) {
+	sf := StatusFile{Filename: "ToolState.json", Event: "ToolState"}
+	if err := RegisterStatusFile(sf); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		statusMu.Lock()
+		delete(statusFiles, "toolstate.json")
+		statusMu.Unlock()
+	})
+
+	if err := RegisterStatusFile(StatusFile{Filename: "toolstate.JSON"}); err != ErrStatusFileRegistered {
+		t.Errorf("registering twice: %v", err)
+	}
+	for _, name := range []string{"", filepath.Join("dir", "Other.json")} {
+		if err := RegisterStatusFile(StatusFile{Filename: name}); err == nil {
+			t.Errorf("registered %q", name)
+		}
+	}
+
+	// Without New, the file decodes as Json.
+	v, err := ParseStatusContents("ToolState.json", []byte(`{ "timestamp":"2025-01-01T12:00:00Z", "Count":3 }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, ok := v.(Json)
+	if !ok {
+		t.Fatalf("decoded as %T, want Json", v)
+	}
+	if n, _ := j.Int64("Count"); n != 3 || GetEventName(j) != "ToolState" {
+		t.Errorf("decoded as %v", j)
+	}
+}
@@ -0,0 +1,454 @@
+package edgo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultRelayAddr is the address a RelayServer listens on by default.
+// It only accepts local connections; to relay to another machine, listen
+// on another address and set a Token.
+const DefaultRelayAddr = "127.0.0.1:7329"
+
+// The relay protocol is newline delimited JSON over TCP. The client
+// sends a relayHello with the server's token and the session and
+// sequence number of the last event it has seen. The server answers
+// with its own relayHello, or one with an Error and closes the
+// connection, then sends RelayEnvelopes: first those the client missed
+// that are still in its buffer, then new ones as they happen.
+
+const (
+	relayHeartbeat = 15 * time.Second // a ping is sent when the server is quiet this long
+	relayTimeout   = 3 * relayHeartbeat
+)
+
+var (
+	ErrRelayKind  = errors.New("edgo: unknown relay event kind")
+	ErrRelayToken = errors.New("edgo: relay token rejected")
+)
+
+// relayHello starts a relay connection. A server's Session changes when
+// it restarts, and its sequence numbers start again from 1.
+type relayHello struct {
+	Session string `json:"session"`
+	Seq     uint64 `json:"seq"`
+	Token   string `json:"token,omitempty"` // from the client
+	Error   string `json:"error,omitempty"` // from the server, if the client is refused
+}
+
+// Relay event kinds, telling the client which type to decode to.
+const (
+	RelayJournal = "journal" // Json
+	RelayRaw     = "raw"     // *RawEvent
+	RelayStatus  = "status"  // a status struct, e.g. *Status
+	RelaySession = "session" // *SessionEvent
+	RelayIdle    = "idle"    // *IdleEvent
+	RelayChange  = "change"  // *StatusChange
+	RelayDiff    = "diff"    // *StatusDiff
+	RelayPing    = "ping"    // no event; keeps the connection alive
+)
+
+// RelayEnvelope is an event as sent by a RelayServer.
+type RelayEnvelope struct {
+	Seq       uint64          `json:"seq"`
+	Kind      string          `json:"kind"`
+	File      string          `json:"file,omitempty"` // the status file, for RelayStatus
+	Source    string          `json:"source,omitempty"`
+	Commander string          `json:"commander,omitempty"`
+	Event     json.RawMessage `json:"event,omitempty"`
+}
+
+// NewRelayEnvelope wraps an event sent by an EliteWatcher.
+func NewRelayEnvelope(e interface{}) (RelayEnvelope, error) {
+	var env RelayEnvelope
+	if s, ok := e.(*SourcedEvent); ok {
+		env.Source, env.Commander, e = s.Source, s.Commander, s.Event
+	}
+	switch e.(type) {
+	case Json:
+		env.Kind = RelayJournal
+	case *RawEvent:
+		env.Kind = RelayRaw
+	case *SessionEvent:
+		env.Kind = RelaySession
+	case *IdleEvent:
+		env.Kind = RelayIdle
+	case *StatusChange:
+		env.Kind = RelayChange
+	case *StatusDiff:
+		env.Kind = RelayDiff
+	default:
+		sf, ok := LookupStatusEvent(GetEventName(e))
+		if !ok {
+			return env, fmt.Errorf("edgo: cannot relay %T", e)
+		}
+		env.Kind = RelayStatus
+		env.File = sf.Filename
+	}
+	var err error
+	env.Event, err = json.Marshal(e)
+	return env, err
+}
+
+// Decode returns the event as the EliteWatcher sent it, wrapped in a
+// SourcedEvent if it was.
+func (env *RelayEnvelope) Decode() (interface{}, error) {
+	var e interface{}
+	var err error
+	switch env.Kind {
+	case RelayJournal:
+		e, err = ParseJournalLine(env.Event)
+	case RelayRaw:
+		e, err = ParseRawEvent(env.Event)
+	case RelayStatus:
+		e, err = ParseStatusContents(env.File, env.Event)
+	case RelaySession:
+		e, err = decodeRelayEvent(env.Event, &SessionEvent{})
+	case RelayIdle:
+		e, err = decodeRelayEvent(env.Event, &IdleEvent{})
+	case RelayChange:
+		e, err = decodeRelayEvent(env.Event, &StatusChange{})
+	case RelayDiff:
+		e, err = decodeRelayEvent(env.Event, &StatusDiff{})
+	default:
+		return nil, ErrRelayKind
+	}
+	if err != nil {
+		return nil, err
+	}
+	if env.Source != "" {
+		e = &SourcedEvent{Source: env.Source, Commander: env.Commander, Event: e}
+	}
+	return e, nil
+}
+
+// decodeRelayEvent decodes into v, keeping numbers as json.Number as
+// ParseJournalLine does.
+func decodeRelayEvent(data []byte, v interface{}) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return v, dec.Decode(v)
+}
+
+// RelayServer streams events to RelayClients over TCP. The latest events
+// are kept in a ring buffer, so that a client that reconnects resumes
+// where it left off.
+type RelayServer struct {
+	Token string // if set, clients must send the same token
+
+	mu      sync.Mutex
+	session string
+	seq     uint64          // of the latest event
+	ring    []RelayEnvelope // the latest events, oldest at head once full
+	head    int
+	size    int
+	notify  chan struct{} // closed and replaced when an event is published
+}
+
+// NewRelayServer returns a server that keeps the latest buffer events.
+func NewRelayServer(buffer int) *RelayServer {
+	if buffer < 1 {
+		buffer = 1
+	}
+	return &RelayServer{
+		session: strconv.FormatInt(time.Now().UnixNano(), 36),
+		ring:    make([]RelayEnvelope, 0, buffer),
+		size:    buffer,
+		notify:  make(chan struct{}),
+	}
+}
+
+// Publish sends an event to the connected clients.
+func (s *RelayServer) Publish(e interface{}) error {
+	env, err := NewRelayEnvelope(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	env.Seq = s.seq
+	if len(s.ring) < s.size {
+		s.ring = append(s.ring, env)
+	} else {
+		s.ring[s.head] = env
+		s.head = (s.head + 1) % s.size
+	}
+	close(s.notify)
+	s.notify = make(chan struct{})
+	return nil
+}
+
+// since returns the buffered events after seq, and a channel that is
+// closed when there are more.
+func (s *RelayServer) since(seq uint64) ([]RelayEnvelope, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first := s.seq - uint64(len(s.ring)) + 1 // of the oldest buffered event
+	if seq+1 < first {
+		if seq > 0 {
+			log.Printf("relay: client missed %d events", first-seq-1)
+		}
+		seq = first - 1
+	}
+	var envs []RelayEnvelope
+	for ; seq < s.seq; seq++ {
+		envs = append(envs, s.ring[(s.head+int(seq+1-first))%len(s.ring)])
+	}
+	return envs, s.notify
+}
+
+// Serve publishes events and accepts clients on l until events is
+// closed or ctx is done. It closes l.
+func (s *RelayServer) Serve(ctx context.Context, l net.Listener, events <-chan interface{}) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := s.Publish(e); err != nil {
+					log.Println("relay:", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.handle(ctx, conn); err != nil && ctx.Err() == nil {
+				log.Println("relay:", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+func (s *RelayServer) handle(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	var hello relayHello
+	conn.SetReadDeadline(time.Now().Add(relayTimeout))
+	if err := json.NewDecoder(conn).Decode(&hello); err != nil {
+		return err
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	w := bufio.NewWriter(conn)
+	enc := json.NewEncoder(w)
+	send := func(v interface{}) error {
+		conn.SetWriteDeadline(time.Now().Add(relayTimeout))
+		return enc.Encode(v)
+	}
+	if subtle.ConstantTimeCompare([]byte(hello.Token), []byte(s.Token)) != 1 {
+		if err := send(relayHello{Error: ErrRelayToken.Error()}); err == nil {
+			w.Flush()
+		}
+		return ErrRelayToken
+	}
+
+	s.mu.Lock()
+	seq := hello.Seq
+	if hello.Session != s.session || seq > s.seq {
+		// The client has not seen this session; send it all we have.
+		seq = 0
+	}
+	reply := relayHello{Session: s.session, Seq: s.seq}
+	s.mu.Unlock()
+	log.Printf("relay: %s connected, resuming after %d", conn.RemoteAddr(), seq)
+
+	if err := send(reply); err != nil {
+		return err
+	}
+
+	heartbeat := time.NewTicker(relayHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		envs, notify := s.since(seq)
+		for _, env := range envs {
+			if err := send(env); err != nil {
+				return err
+			}
+			seq = env.Seq
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		select {
+		case <-notify:
+		case <-heartbeat.C:
+			if err := send(RelayEnvelope{Seq: seq, Kind: RelayPing}); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// RelayClient receives events from a RelayServer and sends them to
+// Journals, as EliteWatcher does, so that consumers work unchanged
+// against a remote game. It reconnects when the connection drops and
+// resumes after the last event it received.
+type RelayClient struct {
+	Addr     string
+	Token    string // sent to the server, which may require it
+	Journals chan interface{}
+	MaxRetry time.Duration // longest wait between reconnects
+
+	session string
+	seq     uint64 // of the last event received
+}
+
+func NewRelayClient(addr string) *RelayClient {
+	return &RelayClient{
+		Addr:     addr,
+		Journals: make(chan interface{}, 10),
+		MaxRetry: 30 * time.Second,
+	}
+}
+
+// Run receives events until ctx is done, then closes Journals.
+func (c *RelayClient) Run(ctx context.Context) error {
+	defer close(c.Journals)
+	first := time.Second
+	if c.MaxRetry > 0 && c.MaxRetry < first {
+		first = c.MaxRetry
+	}
+	retry := first
+	for {
+		connected, err := c.connect(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if connected {
+			retry = first
+		}
+		log.Printf("relay: %s: %v; reconnecting in %s", c.Addr, err, retry)
+		select {
+		case <-time.After(retry):
+		case <-ctx.Done():
+			return nil
+		}
+		if retry *= 2; retry > c.MaxRetry && c.MaxRetry > 0 {
+			retry = c.MaxRetry
+		}
+	}
+}
+
+// connect receives events until the connection fails. It reports
+// whether the server answered.
+func (c *RelayClient) connect(ctx context.Context) (bool, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	conn.SetWriteDeadline(time.Now().Add(relayTimeout))
+	if err := json.NewEncoder(conn).Encode(relayHello{Session: c.session, Seq: c.seq, Token: c.Token}); err != nil {
+		return false, err
+	}
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	var reply relayHello
+	conn.SetReadDeadline(time.Now().Add(relayTimeout))
+	if err := dec.Decode(&reply); err != nil {
+		return false, err
+	}
+	if reply.Error != "" {
+		return false, errors.New("relay: " + reply.Error)
+	}
+	if reply.Session != c.session {
+		if c.session != "" {
+			log.Printf("relay: %s restarted", c.Addr)
+		}
+		c.session, c.seq = reply.Session, 0
+	} else if reply.Seq < c.seq {
+		// The server will send all it has, so take it all.
+		c.seq = 0
+	}
+	log.Printf("relay: connected to %s, resuming after %d", c.Addr, c.seq)
+
+	for {
+		var env RelayEnvelope
+		conn.SetReadDeadline(time.Now().Add(relayTimeout))
+		if err := dec.Decode(&env); err != nil {
+			return true, err
+		}
+		if env.Kind == RelayPing || env.Seq <= c.seq {
+			continue
+		}
+		if c.seq > 0 && env.Seq > c.seq+1 {
+			log.Printf("relay: missed %d events", env.Seq-c.seq-1)
+		}
+		c.seq = env.Seq
+		e, err := env.Decode()
+		if err != nil {
+			log.Println("relay:", err)
+			continue
+		}
+		select {
+		case c.Journals <- e:
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package edgo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func relayEvent(t *testing.T, n int) Json {
+	t.Helper()
+	j, err := ParseJournalLine([]byte(fmt.Sprintf(`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Test", "N":%d }`, n)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func envSeqs(envs []RelayEnvelope) []uint64 {
+	var seqs []uint64
+	for _, env := range envs {
+		seqs = append(seqs, env.Seq)
+	}
+	return seqs
+}
+
+func TestRelaySince(t *testing.T) {
+	s := NewRelayServer(3)
+	if envs, _ := s.since(0); len(envs) != 0 {
+		t.Fatalf("empty server sent %v", envSeqs(envs))
+	}
+	for i := 1; i <= 5; i++ {
+		if err := s.Publish(relayEvent(t, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The buffer has wrapped around and holds events 3 to 5.
+	tests := []struct {
+		after uint64
+		want  string
+	}{
+		{0, "[3 4 5]"},
+		{1, "[3 4 5]"},
+		{2, "[3 4 5]"},
+		{3, "[4 5]"},
+		{4, "[5]"},
+		{5, "[]"},
+	}
+	for _, tt := range tests {
+		envs, _ := s.since(tt.after)
+		if got := fmt.Sprint(envSeqs(envs)); got != tt.want {
+			t.Errorf("since(%d) = %s, want %s", tt.after, got, tt.want)
+		}
+		for _, env := range envs {
+			e, err := env.Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n, _ := e.(Json).Int64("N"); uint64(n) != env.Seq {
+				t.Errorf("event %d holds %d", env.Seq, n)
+			}
+		}
+	}
+
+	// Publishing wakes waiting clients.
+	_, notify := s.since(5)
+	s.Publish(relayEvent(t, 6))
+	select {
+	case <-notify:
+	default:
+		t.Error("notify not closed by Publish")
+	}
+}
+
+func TestRelayEnvelopeTypes(t *testing.T) {
+	status, err := ParseStatusContents("Status.json", []byte(`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Status", "Flags":16 }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idle := &IdleEvent{Level: 2, Seconds: 300}
+	idle.Event = "GameIdle"
+	for _, e := range []interface{}{
+		relayEvent(t, 1),
+		status,
+		idle,
+		&SourcedEvent{Source: "main", Commander: "Jameson", Event: relayEvent(t, 2)},
+	} {
+		env, err := NewRelayEnvelope(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := env.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := json.Marshal(e)
+		if b, _ := json.Marshal(got); string(b) != string(want) {
+			t.Errorf("%T came back as %s, want %s", e, b, want)
+		}
+		if fmt.Sprintf("%T", got) != fmt.Sprintf("%T", e) {
+			t.Errorf("%T came back as %T", e, got)
+		}
+	}
+}
+
+// relayConn connects to s over a pipe, sends hello and returns the reply
+// and a decoder for the envelopes.
+func relayConn(t *testing.T, ctx context.Context, s *RelayServer, hello relayHello) (relayHello, *json.Decoder) {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	go s.handle(ctx, server)
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := json.NewEncoder(client).Encode(hello); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(bufio.NewReader(client))
+	var reply relayHello
+	if err := dec.Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply, dec
+}
+
+func readSeqs(t *testing.T, dec *json.Decoder, n int) []uint64 {
+	t.Helper()
+	var seqs []uint64
+	for len(seqs) < n {
+		var env RelayEnvelope
+		if err := dec.Decode(&env); err != nil {
+			t.Fatal(err)
+		}
+		if env.Kind != RelayPing {
+			seqs = append(seqs, env.Seq)
+		}
+	}
+	return seqs
+}
+
+func TestRelayHandshake(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewRelayServer(10)
+	s.Token = "secret"
+	for i := 1; i <= 4; i++ {
+		s.Publish(relayEvent(t, i))
+	}
+
+	reply, _ := relayConn(t, ctx, s, relayHello{Token: "wrong"})
+	if reply.Error == "" || reply.Session != "" {
+		t.Errorf("wrong token accepted: %+v", reply)
+	}
+
+	reply, dec := relayConn(t, ctx, s, relayHello{Token: "secret"})
+	if reply.Error != "" || reply.Session == "" || reply.Seq != 4 {
+		t.Fatalf("reply %+v", reply)
+	}
+	session := reply.Session
+	if got := fmt.Sprint(readSeqs(t, dec, 4)); got != "[1 2 3 4]" {
+		t.Errorf("new client got %s", got)
+	}
+
+	// Resuming sends only the events after seq.
+	_, dec = relayConn(t, ctx, s, relayHello{Token: "secret", Session: session, Seq: 2})
+	if got := fmt.Sprint(readSeqs(t, dec, 2)); got != "[3 4]" {
+		t.Errorf("resumed client got %s", got)
+	}
+
+	// A client ahead of the server gets everything.
+	_, dec = relayConn(t, ctx, s, relayHello{Token: "secret", Session: session, Seq: 40})
+	if got := fmt.Sprint(readSeqs(t, dec, 4)); got != "[1 2 3 4]" {
+		t.Errorf("client ahead got %s", got)
+	}
+
+	// So does a client of a previous session.
+	_, dec = relayConn(t, ctx, s, relayHello{Token: "secret", Session: "old", Seq: 3})
+	if got := fmt.Sprint(readSeqs(t, dec, 4)); got != "[1 2 3 4]" {
+		t.Errorf("client of an old session got %s", got)
+	}
+
+	// Events published later follow.
+	s.Publish(relayEvent(t, 5))
+	if got := fmt.Sprint(readSeqs(t, dec, 1)); got != "[5]" {
+		t.Errorf("live event %s", got)
+	}
+}
+
+func TestRelayClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewRelayServer(10)
+	s.Token = "secret"
+	events := make(chan interface{})
+	go s.Serve(ctx, l, events)
+	for i := 1; i <= 3; i++ {
+		events <- &SourcedEvent{Source: "main", Commander: "Jameson", Event: relayEvent(t, i)}
+	}
+
+	receive := func(c *RelayClient) int64 {
+		t.Helper()
+		select {
+		case e := <-c.Journals:
+			s, ok := e.(*SourcedEvent)
+			if !ok || s.Source != "main" || s.Commander != "Jameson" {
+				t.Fatalf("received %#v", e)
+			}
+			n, _ := s.Event.(Json).Int64("N")
+			return n
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out")
+		}
+		return 0
+	}
+
+	// The client of a restarted server, whose session and seq no
+	// longer apply, gets every event.
+	c := NewRelayClient(l.Addr().String())
+	c.Token = "secret"
+	c.MaxRetry = 10 * time.Millisecond
+	c.session, c.seq = "old", 2
+	cctx, ccancel := context.WithCancel(ctx)
+	go c.Run(cctx)
+	for want := int64(1); want <= 3; want++ {
+		if n := receive(c); n != want {
+			t.Errorf("received event %d, want %d", n, want)
+		}
+	}
+	ccancel()
+	for range c.Journals {
+	}
+
+	// Reconnecting resumes after the last event received.
+	events <- &SourcedEvent{Source: "main", Commander: "Jameson", Event: relayEvent(t, 4)}
+	c.Journals = make(chan interface{}, 10)
+	go c.Run(ctx)
+	if n := receive(c); n != 4 {
+		t.Errorf("resumed at event %d, want 4", n)
+	}
+
+	// A client with the wrong token receives nothing.
+	bad := NewRelayClient(l.Addr().String())
+	bad.Token = "wrong"
+	bad.MaxRetry = 10 * time.Millisecond
+	go bad.Run(ctx)
+	select {
+	case e := <-bad.Journals:
+		t.Errorf("wrong token received %v", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
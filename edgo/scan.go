@@ -0,0 +1,209 @@
+package edgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// ScanEventHeader scans the top level object of a journal line in a
+// single pass and returns the raw "event" and "timestamp" values.
+// The returned slices alias line, so nothing is allocated. ok is false
+// when the line is not a well formed object, when either key is missing,
+// or when a value contains escapes and needs a full decode.
+func ScanEventHeader(line []byte) (event, timestamp []byte, ok bool) {
+	s := scanner{b: line}
+	s.skipSpace()
+	if !s.consume('{') {
+		return nil, nil, false
+	}
+	for {
+		s.skipSpace()
+		if s.consume('}') {
+			break
+		}
+		start, end, _, ok := s.str()
+		if !ok {
+			return nil, nil, false
+		}
+		key := line[start:end]
+		s.skipSpace()
+		if !s.consume(':') {
+			return nil, nil, false
+		}
+		s.skipSpace()
+
+		isEvent := bytes.Equal(key, []byte("event"))
+		isTimestamp := bytes.Equal(key, []byte("timestamp"))
+		if isEvent || isTimestamp {
+			start, end, escaped, ok := s.str()
+			if !ok || escaped {
+				return nil, nil, false
+			}
+			if isEvent {
+				event = line[start:end]
+			} else {
+				timestamp = line[start:end]
+			}
+			if event != nil && timestamp != nil {
+				return event, timestamp, true
+			}
+		} else if !s.skipValue() {
+			return nil, nil, false
+		}
+
+		s.skipSpace()
+		if s.consume(',') {
+			continue
+		}
+		if s.consume('}') {
+			break
+		}
+		return nil, nil, false
+	}
+	// One of the keys is missing.
+	return nil, nil, false
+}
+
+// scanner is a minimal JSON tokenizer used to skip over values without
+// decoding them.
+type scanner struct {
+	b []byte
+	i int
+}
+
+func (s *scanner) skipSpace() {
+	for s.i < len(s.b) {
+		switch s.b[s.i] {
+		case ' ', '\t', '\r', '\n':
+			s.i++
+		default:
+			return
+		}
+	}
+}
+
+func (s *scanner) consume(c byte) bool {
+	if s.i < len(s.b) && s.b[s.i] == c {
+		s.i++
+		return true
+	}
+	return false
+}
+
+// str scans a string and returns the bounds of its contents.
+func (s *scanner) str() (start, end int, escaped, ok bool) {
+	if !s.consume('"') {
+		return 0, 0, false, false
+	}
+	start = s.i
+	for s.i < len(s.b) {
+		switch s.b[s.i] {
+		case '\\':
+			escaped = true
+			s.i += 2
+		case '"':
+			end = s.i
+			s.i++
+			return start, end, escaped, true
+		default:
+			s.i++
+		}
+	}
+	return 0, 0, false, false
+}
+
+func (s *scanner) skipValue() bool {
+	if s.i >= len(s.b) {
+		return false
+	}
+	switch s.b[s.i] {
+	case '"':
+		_, _, _, ok := s.str()
+		return ok
+	case '{', '[':
+		depth := 0
+		for s.i < len(s.b) {
+			switch s.b[s.i] {
+			case '"':
+				if _, _, _, ok := s.str(); !ok {
+					return false
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					s.i++
+					return true
+				}
+			}
+			s.i++
+		}
+		return false
+	default:
+		start := s.i
+		for s.i < len(s.b) {
+			switch s.b[s.i] {
+			case ',', '}', ']', ' ', '\t', '\r', '\n':
+				return s.i > start
+			}
+			s.i++
+		}
+		return false
+	}
+}
+
+// RawEvent is a journal line whose payload is only decoded when it
+// is first requested.
+type RawEvent struct {
+	Event     string
+	Timestamp string
+	Raw       []byte
+
+	once    sync.Once
+	content Json
+	err     error
+}
+
+// ParseRawEvent scans the event header of a journal line. The rest of
+// the line is decoded lazily by Json.
+func ParseRawEvent(line []byte) (*RawEvent, error) {
+	if event, timestamp, ok := ScanEventHeader(line); ok {
+		return newRawEvent(line, event, timestamp), nil
+	}
+	return decodeRawEvent(line)
+}
+
+// newRawEvent returns the RawEvent for a line whose header
+// ScanEventHeader has already returned.
+func newRawEvent(line, event, timestamp []byte) *RawEvent {
+	return &RawEvent{Event: string(event), Timestamp: string(timestamp), Raw: line}
+}
+
+// decodeRawEvent returns the RawEvent for a line whose header could not
+// be scanned, by decoding it eagerly.
+func decodeRawEvent(line []byte) (*RawEvent, error) {
+	r := &RawEvent{Raw: line}
+	content, err := r.Json()
+	if err != nil {
+		return nil, err
+	}
+	r.Event, _ = content.String("event")
+	r.Timestamp, _ = content.String("timestamp")
+	return r, nil
+}
+
+// Json decodes the full journal line.
+func (r *RawEvent) Json() (Json, error) {
+	r.once.Do(func() {
+		r.content, r.err = ParseJournalLine(r.Raw)
+	})
+	return r.content, r.err
+}
+
+// MarshalJSON returns the original journal line.
+func (r *RawEvent) MarshalJSON() ([]byte, error) {
+	return json.RawMessage(bytes.TrimSpace(r.Raw)).MarshalJSON()
+}
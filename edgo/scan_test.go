@@ -0,0 +1,175 @@
+package edgo
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestScanEventHeader(t *testing.T) {
+	tests := []struct {
+		name, line string
+		event, ts  string
+		ok         bool
+	}{
+		{"plain",
+			`{ "timestamp":"2025-01-01T12:00:00Z", "event":"FSDJump", "StarSystem":"Sol" }`,
+			"FSDJump", "2025-01-01T12:00:00Z", true},
+		{"event first",
+			`{"event":"Docked","timestamp":"2025-01-01T12:00:00Z"}`,
+			"Docked", "2025-01-01T12:00:00Z", true},
+		{"escaped quotes before event",
+			`{ "timestamp":"2025-01-01T12:00:00Z", "Message":"he said \"event\":\"x\"", "event":"ReceiveText" }`,
+			"ReceiveText", "2025-01-01T12:00:00Z", true},
+		{"escaped backslash before event",
+			`{ "timestamp":"2025-01-01T12:00:00Z", "Path":"C:\\", "event":"Music" }`,
+			"Music", "2025-01-01T12:00:00Z", true},
+		{"nested objects before event",
+			`{ "timestamp":"2025-01-01T12:00:00Z", "Ship":{ "event":"Nested", "Modules":[ { "Slot":"}" }, [1,2] ] }, "event":"Loadout" }`,
+			"Loadout", "2025-01-01T12:00:00Z", true},
+		{"escaped event", `{ "timestamp":"2025-01-01T12:00:00Z", "event":"FSD\u004aump" }`, "", "", false},
+		{"missing event", `{ "timestamp":"2025-01-01T12:00:00Z", "Ship":{ "event":"Nested" } }`, "", "", false},
+		{"missing timestamp", `{ "event":"FSDJump" }`, "", "", false},
+		{"empty object", `{}`, "", "", false},
+		{"not an object", `[ "event", "FSDJump" ]`, "", "", false},
+		{"empty", ``, "", "", false},
+	}
+	for _, tt := range tests {
+		event, ts, ok := ScanEventHeader([]byte(tt.line))
+		if ok != tt.ok || string(event) != tt.event || string(ts) != tt.ts {
+			t.Errorf("%s: got %q, %q, %v; want %q, %q, %v", tt.name, event, ts, ok, tt.event, tt.ts, tt.ok)
+		}
+	}
+}
+
+func TestParseRawEvent(t *testing.T) {
+	tests := []struct {
+		name, line string
+		event, ts  string
+		ok         bool
+	}{
+		{"scanned", `{ "timestamp":"2025-01-01T12:00:00Z", "event":"FSDJump", "StarSystem":"Sol" }`,
+			"FSDJump", "2025-01-01T12:00:00Z", true},
+		{"escaped event", `{ "timestamp":"2025-01-01T12:00:00Z", "event":"FSD\u004aump" }`,
+			"FSDJump", "2025-01-01T12:00:00Z", true},
+		{"missing timestamp", `{ "event":"FSDJump" }`, "FSDJump", "", true},
+		{"not json", `{ "event":`, "", "", false},
+	}
+	for _, tt := range tests {
+		r, err := ParseRawEvent([]byte(tt.line))
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if r.Event != tt.event || r.Timestamp != tt.ts {
+			t.Errorf("%s: got %q, %q; want %q, %q", tt.name, r.Event, r.Timestamp, tt.event, tt.ts)
+		}
+		if j, err := r.Json(); err != nil || GetEventName(j) != tt.event {
+			t.Errorf("%s: decoded %v, %v", tt.name, j, err)
+		}
+	}
+}
+
+func TestScanEventHeaderTruncated(t *testing.T) {
+	line := `{ "Ship":{ "Name":"a\"b", "Modules":[ 1, 2 ] }, "timestamp":"2025-01-01T12:00:00Z", "event":"Loadout" }`
+	if _, _, ok := ScanEventHeader([]byte(line)); !ok {
+		t.Fatal("full line not scanned")
+	}
+	// Cut before the event value ends, nothing may be returned.
+	end := len(line) - len(`Loadout" }`)
+	for i := 0; i < end; i++ {
+		if event, ts, ok := ScanEventHeader([]byte(line[:i])); ok {
+			t.Errorf("%q: got %q, %q", line[:i], event, ts)
+		}
+	}
+}
+
+// benchJournal returns a journal of about size bytes, with a mix of small
+// events and large ones like the game writes.
+func benchJournal(size int) [][]byte {
+	var lines [][]byte
+	n := 0
+	for i := 0; n < size; i++ {
+		var line string
+		ts := fmt.Sprintf("2025-01-01T%02d:%02d:%02dZ", i/3600%24, i/60%60, i%60)
+		switch i % 4 {
+		case 0:
+			line = fmt.Sprintf(`{ "timestamp":"%s", "event":"FSDJump", "StarSystem":"System %d", "SystemAddress":%d, "StarPos":[%d.5,1.25,-3.0], "JumpDist":12.5, "FuelUsed":1.2, "FuelLevel":14.8 }`, ts, i, i*977, i)
+		case 1:
+			var modules bytes.Buffer
+			for m := 0; m < 40; m++ {
+				fmt.Fprintf(&modules, `{ "Slot":"Slot%02d", "Item":"int_module_size%d", "On":true, "Priority":0, "Health":1.0, "Value":%d }, `, m, m%8, m*1000)
+			}
+			line = fmt.Sprintf(`{ "timestamp":"%s", "Ship":"python", "ShipID":7, "Modules":[ %s{ "Slot":"End" } ], "event":"Loadout" }`, ts, modules.String())
+		case 2:
+			line = fmt.Sprintf(`{ "timestamp":"%s", "event":"ReceiveText", "From":"Pilot %d", "Message":"o7 \"cmdr\"", "Channel":"local" }`, ts, i)
+		default:
+			line = fmt.Sprintf(`{ "timestamp":"%s", "event":"Music", "MusicTrack":"Supercruise" }`, ts)
+		}
+		lines = append(lines, []byte(line))
+		n += len(line) + 1
+	}
+	return lines
+}
+
+func journalSize(lines [][]byte) int64 {
+	var n int64
+	for _, l := range lines {
+		n += int64(len(l)) + 1
+	}
+	return n
+}
+
+func BenchmarkScanEventHeader(b *testing.B) {
+	lines := benchJournal(4 << 20)
+	b.SetBytes(journalSize(lines))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			if _, _, ok := ScanEventHeader(line); !ok {
+				b.Fatalf("not scanned: %s", line)
+			}
+		}
+	}
+}
+
+// BenchmarkFilteredTail compares tailing a journal for one event, by
+// scanning headers and decoding only the matches, with decoding every
+// line.
+func BenchmarkFilteredTail(b *testing.B) {
+	lines := benchJournal(4 << 20)
+	b.Run("scan", func(b *testing.B) {
+		b.SetBytes(journalSize(lines))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, line := range lines {
+				event, _, ok := ScanEventHeader(line)
+				if ok && string(event) != "FSDJump" {
+					continue
+				}
+				if _, err := ParseJournalLine(line); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("parse", func(b *testing.B) {
+		b.SetBytes(journalSize(lines))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, line := range lines {
+				j, err := ParseJournalLine(line)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if GetEventName(j) != "FSDJump" {
+					continue
+				}
+			}
+		}
+	})
+}
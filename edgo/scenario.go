@@ -0,0 +1,517 @@
+package edgo
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scenario is a scripted game session for a Generator. A scenario file
+// has an action per line, followed by its argument if it takes one:
+//
+//	# Jameson flies from Sol to Alpha Centauri
+//	commander Jameson
+//	ship SideWinder
+//	start 2025-01-01T12:00:00Z
+//	launch Sol at Abraham Lincoln
+//	undock
+//	jump Alpha Centauri
+//	interdicted by Pirate Pete
+//	wait 2m
+//	dock Hutton Orbital
+//	quit
+//
+// commander, ship and start must come before launch. launch starts the
+// game in a system, docked if a station is given. supercruise, jump,
+// interdicted, dock and undock fly the ship, wait lets time pass, and
+// quit ends the session.
+type Scenario []ScenarioStep
+
+type ScenarioStep struct {
+	Line   int
+	Action string
+	Arg    string
+}
+
+var scenarioActions = map[string]func(g *Generator, arg string) error{
+	"commander":   (*Generator).setCommander,
+	"ship":        (*Generator).setShip,
+	"start":       (*Generator).setStart,
+	"launch":      (*Generator).launch,
+	"undock":      (*Generator).undock,
+	"supercruise": (*Generator).supercruise,
+	"jump":        (*Generator).jump,
+	"interdicted": (*Generator).interdicted,
+	"dock":        (*Generator).dock,
+	"wait":        (*Generator).wait,
+	"quit":        (*Generator).quit,
+}
+
+// ParseScenario reads a scenario file. Blank lines and lines starting
+// with # are ignored.
+func ParseScenario(r io.Reader) (Scenario, error) {
+	var s Scenario
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		f := strings.SplitN(text, " ", 2)
+		step := ScenarioStep{Line: line, Action: strings.ToLower(f[0])}
+		if len(f) > 1 {
+			step.Arg = strings.TrimSpace(f[1])
+		}
+		if _, ok := scenarioActions[step.Action]; !ok {
+			return nil, fmt.Errorf("scenario line %d: unknown action %q", line, f[0])
+		}
+		s = append(s, step)
+	}
+	return s, scanner.Err()
+}
+
+// Generator plays a Scenario, writing a journal and Status.json to Dir
+// as the game would.
+type Generator struct {
+	Dir      string
+	Start    time.Time // time of the first event; defaults to now
+	Realtime bool      // sleep between events as the game would
+	Speed    float64   // speeds up Realtime; defaults to 1
+	Replace  bool      // replace a journal of the same name instead of failing
+
+	now       time.Time
+	journal   *JournalWriter
+	commander string
+	ship      string
+	system    string
+	station   string
+	flags     int
+	fuel      float64
+}
+
+const generatorFuelCapacity = 16
+
+// Run plays the scenario. A session still running at the end is ended
+// as if by quit.
+func (g *Generator) Run(s Scenario) error {
+	if g.commander == "" {
+		g.commander = "Jameson"
+	}
+	if g.ship == "" {
+		g.ship = "sidewinder"
+	}
+	g.now = g.Start
+	if g.now.IsZero() {
+		g.now = time.Now()
+	}
+	for _, step := range s {
+		if err := scenarioActions[step.Action](g, step.Arg); err != nil {
+			return fmt.Errorf("scenario line %d: %s: %v", step.Line, step.Action, err)
+		}
+	}
+	if g.journal != nil {
+		return g.quit("")
+	}
+	return nil
+}
+
+// advance moves the clock on, sleeping in realtime mode.
+func (g *Generator) advance(d time.Duration) {
+	g.now = g.now.Add(d)
+	if g.Realtime {
+		speed := g.Speed
+		if speed <= 0 {
+			speed = 1
+		}
+		time.Sleep(time.Duration(float64(d) / speed))
+	}
+}
+
+// event advances the clock a second and writes a journal event.
+func (g *Generator) event(name string, fields ...Field) error {
+	g.advance(time.Second)
+	f := append(Fields{{"timestamp", JournalTime(g.now)}, {"event", name}}, fields...)
+	return g.journal.Write(f)
+}
+
+// status writes Status.json for the current flags.
+func (g *Generator) status() error {
+	return WriteStatusFile(g.Dir, Fields{
+		{"timestamp", JournalTime(g.now)},
+		{"event", "Status"},
+		{"Flags", g.flags},
+		{"Flags2", 0},
+		{"Pips", []interface{}{4, 4, 4}},
+		{"FireGroup", 0},
+		{"GuiFocus", 0},
+		{"Fuel", Fields{
+			{"FuelMain", journalFloat(g.fuel)},
+			{"FuelReservoir", journalFloat(0.5)},
+		}},
+		{"Cargo", journalFloat(0)},
+		{"LegalState", "Clean"},
+		{"Balance", 1000000},
+	})
+}
+
+const (
+	generatorDocked      = FlagDocked | FlagLandingGearDown | FlagShieldsUp | FlagFsdMassLocked | FlagInMainShip
+	generatorNormalSpace = FlagShieldsUp | FlagInMainShip
+	generatorSupercruise = FlagShieldsUp | FlagSupercruise | FlagInMainShip
+)
+
+func (g *Generator) running() error {
+	if g.journal == nil {
+		return errors.New("game not launched")
+	}
+	return nil
+}
+
+func (g *Generator) setCommander(arg string) error {
+	if g.journal != nil {
+		return errors.New("must come before launch")
+	}
+	g.commander = arg
+	return nil
+}
+
+func (g *Generator) setShip(arg string) error {
+	if g.journal != nil {
+		return errors.New("must come before launch")
+	}
+	g.ship = strings.ToLower(arg)
+	return nil
+}
+
+func (g *Generator) setStart(arg string) error {
+	if g.journal != nil {
+		return errors.New("must come before launch")
+	}
+	t, err := time.Parse(time.RFC3339, arg)
+	if err == nil {
+		g.Start = t
+		g.now = t
+	}
+	return err
+}
+
+// launch starts a session: "launch System" or "launch System at Station".
+func (g *Generator) launch(arg string) error {
+	if g.journal != nil {
+		return errors.New("game already running")
+	}
+	g.system = arg
+	g.station = ""
+	if idx := strings.Index(arg, " at "); idx >= 0 {
+		g.system, g.station = arg[:idx], strings.TrimSpace(arg[idx+len(" at "):])
+	}
+	if g.system == "" {
+		return errors.New("no system")
+	}
+	g.fuel = generatorFuelCapacity
+
+	if g.Replace {
+		err := os.Remove(filepath.Join(g.Dir, JournalName(g.now, 1)))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	journal, err := CreateJournal(g.Dir, g.now, 1)
+	if err != nil {
+		return err
+	}
+	g.journal = journal
+
+	fid := fmt.Sprintf("F%d", nameID(g.commander)%10000000)
+	if err := g.event("Fileheader",
+		Field{"part", 1},
+		Field{"language", "English/UK"},
+		Field{"Odyssey", true},
+		Field{"gameversion", "4.0.0.1900"},
+		Field{"build", "r300000/r0 "}); err != nil {
+		return err
+	}
+	if err := g.event("Commander", Field{"FID", fid}, Field{"Name", g.commander}); err != nil {
+		return err
+	}
+	if err := g.event("LoadGame",
+		Field{"FID", fid},
+		Field{"Commander", g.commander},
+		Field{"Horizons", true},
+		Field{"Odyssey", true},
+		Field{"Ship", g.ship},
+		Field{"ShipID", 1},
+		Field{"ShipName", ""},
+		Field{"ShipIdent", ""},
+		Field{"FuelLevel", journalFloat(g.fuel)},
+		Field{"FuelCapacity", journalFloat(generatorFuelCapacity)},
+		Field{"GameMode", "Solo"},
+		Field{"Credits", 1000000},
+		Field{"Loan", 0}); err != nil {
+		return err
+	}
+
+	location := Fields{{"Docked", g.station != ""}}
+	if g.station != "" {
+		location = append(location,
+			Field{"StationName", g.station},
+			Field{"StationType", "Coriolis"},
+			Field{"MarketID", marketID(g.station)})
+		g.flags = generatorDocked
+	} else {
+		g.flags = generatorNormalSpace
+	}
+	location = append(location, g.systemFields()...)
+	if err := g.event("Location", location...); err != nil {
+		return err
+	}
+	return g.status()
+}
+
+func (g *Generator) undock(arg string) error {
+	if err := g.running(); err != nil {
+		return err
+	}
+	if g.station == "" {
+		return errors.New("not docked")
+	}
+	g.advance(5 * time.Second)
+	err := g.event("Undocked",
+		Field{"StationName", g.station},
+		Field{"StationType", "Coriolis"},
+		Field{"MarketID", marketID(g.station)})
+	if err != nil {
+		return err
+	}
+	g.station = ""
+	g.flags = generatorNormalSpace
+	g.advance(20 * time.Second)
+	return g.status()
+}
+
+func (g *Generator) supercruise(arg string) error {
+	if err := g.running(); err != nil {
+		return err
+	}
+	if g.station != "" {
+		return errors.New("docked")
+	}
+	if g.flags&FlagSupercruise != 0 {
+		return nil
+	}
+	g.advance(15 * time.Second)
+	err := g.event("SupercruiseEntry",
+		Field{"StarSystem", g.system},
+		Field{"SystemAddress", systemAddress(g.system)})
+	if err != nil {
+		return err
+	}
+	g.flags = generatorSupercruise
+	return g.status()
+}
+
+func (g *Generator) jump(arg string) error {
+	if err := g.running(); err != nil {
+		return err
+	}
+	if g.station != "" {
+		return errors.New("docked")
+	}
+	if arg == "" {
+		return errors.New("no system")
+	}
+	from := starPos(g.system)
+	g.advance(10 * time.Second)
+	err := g.event("StartJump",
+		Field{"JumpType", "Hyperspace"},
+		Field{"StarSystem", arg},
+		Field{"SystemAddress", systemAddress(arg)},
+		Field{"StarClass", "K"})
+	if err != nil {
+		return err
+	}
+	g.flags |= FlagFsdCharging
+	if err := g.status(); err != nil {
+		return err
+	}
+
+	g.advance(15 * time.Second)
+	g.system = arg
+	to := starPos(g.system)
+	dist := math.Sqrt((to[0]-from[0])*(to[0]-from[0]) + (to[1]-from[1])*(to[1]-from[1]) + (to[2]-from[2])*(to[2]-from[2]))
+	used := math.Min(g.fuel, 0.2+dist/100)
+	g.fuel -= used
+	fields := append(g.systemFields(),
+		Field{"JumpDist", json.Number(strconv.FormatFloat(dist, 'f', 3, 64))},
+		Field{"FuelUsed", journalFloat(used)},
+		Field{"FuelLevel", journalFloat(g.fuel)})
+	if err := g.event("FSDJump", fields...); err != nil {
+		return err
+	}
+	g.flags = generatorSupercruise
+	return g.status()
+}
+
+// interdicted drops the ship out of supercruise: "interdicted" or
+// "interdicted by Name".
+func (g *Generator) interdicted(arg string) error {
+	if err := g.running(); err != nil {
+		return err
+	}
+	if g.flags&FlagSupercruise == 0 {
+		return errors.New("not in supercruise")
+	}
+	interdictor := strings.TrimSpace(strings.TrimPrefix(arg, "by "))
+	if interdictor == "" {
+		interdictor = "Pirate"
+	}
+	g.flags |= FlagBeingInterdicted
+	if err := g.status(); err != nil {
+		return err
+	}
+	g.advance(10 * time.Second)
+	err := g.event("Interdicted",
+		Field{"Submitted", false},
+		Field{"Interdictor", interdictor},
+		Field{"IsPlayer", false})
+	if err != nil {
+		return err
+	}
+	g.flags = generatorNormalSpace
+	return g.status()
+}
+
+func (g *Generator) dock(arg string) error {
+	if err := g.running(); err != nil {
+		return err
+	}
+	if g.station != "" {
+		return errors.New("already docked")
+	}
+	if arg == "" {
+		return errors.New("no station")
+	}
+	if g.flags&FlagSupercruise != 0 {
+		g.advance(2 * time.Minute)
+		err := g.event("SupercruiseExit",
+			Field{"StarSystem", g.system},
+			Field{"SystemAddress", systemAddress(g.system)},
+			Field{"Body", arg},
+			Field{"BodyType", "Station"})
+		if err != nil {
+			return err
+		}
+		g.flags = generatorNormalSpace
+		if err := g.status(); err != nil {
+			return err
+		}
+	}
+
+	station := []Field{
+		{"MarketID", marketID(arg)},
+		{"StationName", arg},
+		{"StationType", "Coriolis"},
+	}
+	g.advance(20 * time.Second)
+	if err := g.event("DockingRequested", station...); err != nil {
+		return err
+	}
+	if err := g.event("DockingGranted", append([]Field{{"LandingPad", 7}}, station...)...); err != nil {
+		return err
+	}
+	g.flags |= FlagLandingGearDown
+	if err := g.status(); err != nil {
+		return err
+	}
+	g.advance(time.Minute)
+	err := g.event("Docked",
+		Field{"StationName", arg},
+		Field{"StationType", "Coriolis"},
+		Field{"StarSystem", g.system},
+		Field{"SystemAddress", systemAddress(g.system)},
+		Field{"MarketID", marketID(arg)})
+	if err != nil {
+		return err
+	}
+	g.station = arg
+	g.flags = generatorDocked
+	return g.status()
+}
+
+func (g *Generator) wait(arg string) error {
+	d, err := time.ParseDuration(arg)
+	if err == nil {
+		g.advance(d)
+	}
+	return err
+}
+
+func (g *Generator) quit(arg string) error {
+	if err := g.running(); err != nil {
+		return err
+	}
+	err := g.event("Shutdown")
+	if cerr := g.journal.Close(); err == nil {
+		err = cerr
+	}
+	g.journal = nil
+	return err
+}
+
+// systemFields returns the fields that describe the current system.
+func (g *Generator) systemFields() Fields {
+	pos := starPos(g.system)
+	return Fields{
+		{"StarSystem", g.system},
+		{"SystemAddress", systemAddress(g.system)},
+		{"StarPos", []interface{}{
+			journalCoord(pos[0]), journalCoord(pos[1]), journalCoord(pos[2]),
+		}},
+		{"Body", g.system},
+		{"BodyID", 0},
+		{"BodyType", "Star"},
+	}
+}
+
+// nameID derives a stable identifier from a name, so that a system or
+// station has the same address and position in every scenario.
+func nameID(name string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(strings.ToLower(name)))
+	return h.Sum64()
+}
+
+func systemAddress(system string) int64 {
+	return int64(nameID(system) >> 24)
+}
+
+func marketID(station string) int64 {
+	return 3200000000 + int64(nameID(station)%100000000)
+}
+
+// starPos places a system within a few hundred light years of Sol.
+func starPos(system string) [3]float64 {
+	id := nameID(system)
+	var pos [3]float64
+	for i := range pos {
+		pos[i] = float64(int64(id>>(uint(i)*16)&0xffff)-0x8000) / 64
+	}
+	return pos
+}
+
+func journalFloat(f float64) json.Number {
+	return json.Number(strconv.FormatFloat(f, 'f', 6, 64))
+}
+
+func journalCoord(f float64) json.Number {
+	return json.Number(strconv.FormatFloat(f, 'f', 5, 64))
+}
@@ -0,0 +1,48 @@
+package edgo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGenerateTestdata checks that the journal in testdata is what its
+// scenario generates, with the command given in the scenario.
+func TestGenerateTestdata(t *testing.T) {
+	f, err := os.Open(filepath.Join("..", "testdata", "scenario.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scenario, err := ParseScenario(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := (&Generator{Dir: dir}).Run(scenario); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Generator{Dir: dir}).Run(scenario); err == nil {
+		t.Error("generating twice did not fail")
+	}
+	if err := (&Generator{Dir: dir, Replace: true}).Run(scenario); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range files {
+		got, _ := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		want, err := ioutil.ReadFile(filepath.Join("..", "testdata", "journals", fi.Name()))
+		if err != nil {
+			t.Errorf("%s is not in testdata: %v", fi.Name(), err)
+		} else if !bytes.Equal(got, want) {
+			t.Errorf("%s differs from testdata; regenerate it with the command in the scenario", fi.Name())
+		}
+	}
+}
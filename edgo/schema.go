@@ -0,0 +1,325 @@
+package edgo
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Schema accumulates the fields seen in events, per event name.
+type Schema struct {
+	Events map[string]*EventSchema
+}
+
+// EventSchema describes the fields of one event.
+type EventSchema struct {
+	Name   string
+	Count  int                     // events seen
+	Fields map[string]*FieldSchema // by path
+}
+
+// FieldSchema describes one field. Paths are dotted, with "[]" for
+// array elements, so "Route[].StarSystem" is a field of each entry of
+// the Route array.
+type FieldSchema struct {
+	Path     string
+	Count    int            // times seen, counting each array element
+	Events   int            // events it was seen in
+	Types    map[string]int // JSON Schema type name -> times seen
+	Examples []string       // a few distinct example values
+}
+
+const maxSchemaExamples = 3
+
+func NewSchema() *Schema {
+	return &Schema{Events: make(map[string]*EventSchema)}
+}
+
+// Add records the fields of an event.
+func (s *Schema) Add(content Json) {
+	name := GetEventName(content)
+	if name == "" {
+		return
+	}
+	e := s.Events[name]
+	if e == nil {
+		e = &EventSchema{Name: name, Fields: make(map[string]*FieldSchema)}
+		s.Events[name] = e
+	}
+	e.Count++
+	e.addObject("", map[string]interface{}(content), make(map[string]bool))
+}
+
+// addObject records the fields of obj. seen holds the paths already
+// seen in the event.
+func (e *EventSchema) addObject(prefix string, obj map[string]interface{}, seen map[string]bool) {
+	for k, v := range obj {
+		e.addValue(prefix+k, v, seen)
+	}
+}
+
+func (e *EventSchema) addValue(path string, v interface{}, seen map[string]bool) {
+	f := e.Fields[path]
+	if f == nil {
+		f = &FieldSchema{Path: path, Types: make(map[string]int)}
+		e.Fields[path] = f
+	}
+	f.Count++
+	if !seen[path] {
+		seen[path] = true
+		f.Events++
+	}
+	t := schemaType(v)
+	f.Types[t]++
+
+	switch c := v.(type) {
+	case map[string]interface{}:
+		e.addObject(path+".", c, seen)
+	case Json:
+		e.addObject(path+".", c, seen)
+	case []interface{}:
+		for _, elem := range c {
+			e.addValue(path+"[]", elem, seen)
+		}
+	default:
+		if len(f.Examples) < maxSchemaExamples {
+			b, _ := json.Marshal(v)
+			example := string(b)
+			for _, x := range f.Examples {
+				if x == example {
+					return
+				}
+			}
+			f.Examples = append(f.Examples, example)
+		}
+	}
+}
+
+func schemaType(v interface{}) string {
+	switch n := v.(type) {
+	case string:
+		return "string"
+	case json.Number:
+		if strings.ContainsAny(n.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]interface{}, Json:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return "null"
+	}
+}
+
+// Names returns the event names, sorted.
+func (s *Schema) Names() []string {
+	var names []string
+	for name := range s.Events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Paths returns the field paths of an event, sorted.
+func (e *EventSchema) Paths() []string {
+	var paths []string
+	for p := range e.Fields {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Optional reports whether the field at path was missing from some of
+// the events that contain its parent. Events are counted rather than
+// objects, so that the elements of an array, at "X[]", are optional
+// when the array is empty in some events, however many elements the
+// others hold.
+func (e *EventSchema) Optional(path string) bool {
+	parent := e.Count
+	if p := e.Fields[schemaParent(path)]; p != nil {
+		parent = p.Events
+	}
+	return e.Fields[path].Events < parent
+}
+
+// schemaParent returns the path of the object or array holding path,
+// or "" for a top level field.
+func schemaParent(path string) string {
+	if strings.HasSuffix(path, "[]") {
+		return path[:len(path)-len("[]")]
+	}
+	if idx := strings.LastIndex(path, "."); idx >= 0 {
+		return path[:idx]
+	}
+	return ""
+}
+
+// TypeNames returns the JSON types seen for a field, sorted.
+func (f *FieldSchema) TypeNames() []string {
+	var types []string
+	for t := range f.Types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// JSONSchema returns a JSON Schema document with a definition for each
+// event.
+func (s *Schema) JSONSchema() map[string]interface{} {
+	defs := make(map[string]interface{})
+	for name, e := range s.Events {
+		defs[name] = e.jsonSchema("")
+	}
+	return map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"definitions": defs,
+	}
+}
+
+// jsonSchema returns the schema of the object at prefix.
+func (e *EventSchema) jsonSchema(prefix string) map[string]interface{} {
+	props := make(map[string]interface{})
+	var required []string
+	for _, path := range e.Paths() {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		key := path[len(prefix):]
+		if strings.ContainsAny(key, ".[") {
+			continue
+		}
+		props[key] = e.fieldSchema(path)
+		if !e.Optional(path) {
+			required = append(required, key)
+		}
+	}
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (e *EventSchema) fieldSchema(path string) map[string]interface{} {
+	f := e.Fields[path]
+	types := f.TypeNames()
+
+	var schema map[string]interface{}
+	if f.Types["object"] > 0 {
+		schema = e.jsonSchema(path + ".")
+	} else {
+		schema = make(map[string]interface{})
+	}
+	if f.Types["array"] > 0 {
+		if _, ok := e.Fields[path+"[]"]; ok {
+			schema["items"] = e.fieldSchema(path + "[]")
+		}
+	}
+	if len(types) == 1 {
+		schema["type"] = types[0]
+	} else {
+		schema["type"] = types
+	}
+	if len(f.Examples) > 0 {
+		var examples []interface{}
+		for _, x := range f.Examples {
+			examples = append(examples, json.RawMessage(x))
+		}
+		schema["examples"] = examples
+	}
+	return schema
+}
+
+// StructFields returns the JSON field paths that a struct type decodes,
+// using the same path syntax as FieldSchema.
+func StructFields(t reflect.Type) []string {
+	var paths []string
+	structFields(t, "", &paths)
+	sort.Strings(paths)
+	return paths
+}
+
+func structFields(t reflect.Type, prefix string, paths *[]string) {
+	for _, f := range jsonFields(t) {
+		path := prefix + f.name
+		*paths = append(*paths, path)
+
+		ft := indirectType(f.typ)
+		for ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array {
+			path += "[]"
+			*paths = append(*paths, path)
+			ft = indirectType(ft.Elem())
+		}
+		if ft.Kind() == reflect.Struct {
+			structFields(ft, path+".", paths)
+		}
+	}
+}
+
+// jsonField is a struct field as encoding/json sees it.
+type jsonField struct {
+	name     string
+	typ      reflect.Type
+	optional bool // tagged edgo:"optional"; the game leaves it out at times
+}
+
+// jsonFields returns the fields that a struct type decodes, with the
+// fields of embedded structs flattened into it.
+func jsonFields(t reflect.Type) []jsonField {
+	t = indirectType(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := jsonFieldName(f)
+		if name == "-" || (f.PkgPath != "" && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			fields = append(fields, jsonFields(f.Type)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{name, f.Type, f.Tag.Get("edgo") == "optional"})
+	}
+	return fields
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// jsonFieldName returns the name from a field's json tag.
+func jsonFieldName(f reflect.StructField) string {
+	return strings.Split(f.Tag.Get("json"), ",")[0]
+}
+
+// KnownFields returns the field paths decoded by the registered status
+// file type for an event, or nil if there is none.
+func KnownFields(event string) []string {
+	sf, ok := LookupStatusEvent(event)
+	if !ok || sf.New == nil {
+		return nil
+	}
+	return StructFields(reflect.TypeOf(sf.New()))
+}
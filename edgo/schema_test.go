@@ -0,0 +1,72 @@
+package edgo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaOptional(t *testing.T) {
+	s := NewSchema()
+	for _, line := range []string{
+		`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Route", "Route":[ { "StarSystem":"Sol", "StarClass":"G" }, { "StarSystem":"Alpha Centauri" }, { "StarSystem":"Barnard's Star" } ], "Dest":{ "Name":"Sol" } }`,
+		`{ "timestamp":"2025-01-01T12:01:00Z", "event":"Route", "Route":[ ], "Dest":{ "Name":"Sol", "Body":1 } }`,
+		`{ "timestamp":"2025-01-01T12:02:00Z", "event":"Route", "Route":[ { "StarSystem":"Sol" } ], "Tags":[ "a" ] }`,
+	} {
+		s.Add(journalEvent(t, line))
+	}
+	e := s.Events["Route"]
+	if e == nil || e.Count != 3 {
+		t.Fatalf("Route schema %+v", e)
+	}
+
+	tests := []struct {
+		path     string
+		optional bool
+	}{
+		{"timestamp", false},
+		{"Route", false},
+		// Empty in one event, though there are more elements than
+		// events.
+		{"Route[]", true},
+		// In every event with route entries, though not in every entry.
+		{"Route[].StarSystem", false},
+		{"Route[].StarClass", true},
+		{"Dest", true},
+		{"Dest.Name", false},
+		{"Dest.Body", true},
+		{"Tags", true},
+		// In the only event that has Tags.
+		{"Tags[]", false},
+	}
+	for _, tt := range tests {
+		if _, ok := e.Fields[tt.path]; !ok {
+			t.Errorf("no field %s", tt.path)
+			continue
+		}
+		if got := e.Optional(tt.path); got != tt.optional {
+			t.Errorf("Optional(%s) = %v, want %v", tt.path, got, tt.optional)
+		}
+	}
+
+	if f := e.Fields["Route[].StarSystem"]; f.Count != 4 || f.Events != 2 {
+		t.Errorf("Route[].StarSystem seen %d times in %d events", f.Count, f.Events)
+	}
+	if types := e.Fields["Dest.Body"].TypeNames(); !reflect.DeepEqual(types, []string{"integer"}) {
+		t.Errorf("Dest.Body types %v", types)
+	}
+
+	schema := e.jsonSchema("")
+	if got := strings.Join(schema["required"].([]string), " "); got != "Route event timestamp" {
+		t.Errorf("required %s", got)
+	}
+}
+
+func TestStructFields(t *testing.T) {
+	got := strings.Join(StructFields(reflect.TypeOf(Cargo{})), " ")
+	want := "Count Inventory Inventory[] Inventory[].Count Inventory[].MissionID Inventory[].Name " +
+		"Inventory[].Name_Localised Inventory[].Stolen Vessel event timestamp"
+	if got != want {
+		t.Errorf("StructFields(Cargo)\n%s\nwant\n%s", got, want)
+	}
+}
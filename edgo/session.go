@@ -0,0 +1,171 @@
+package edgo
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SessionEvent marks a journal session boundary. Event is one of
+// "SessionStart", "SessionContinued" or "SessionEnd".
+type SessionEvent struct {
+	Base
+	SessionID string
+	Part      int
+	Filename  string
+}
+
+// Session is a game session, which the game may split across several
+// journal files when it gets long.
+type Session struct {
+	ID    string
+	Parts []string // journal files, in order
+}
+
+// SessionID returns the session ID for a journal filename, which is the
+// datestamp shared by all of its parts.
+func SessionID(filename string) string {
+	base := filepath.Base(filename)
+	if !journalRE.MatchString(strings.ToLower(base)) {
+		return ""
+	}
+	f := strings.Split(base, ".")
+	return f[1]
+}
+
+// journalPart returns the part number from the Fileheader of a journal
+// file, or 0 if it cannot be read.
+func journalPart(filename string) int {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	line, _ := bufio.NewReader(f).ReadBytes('\n')
+	if len(line) == 0 {
+		return 0
+	}
+	content, err := ParseJournalLine(line)
+	if err != nil || GetEventName(content) != "Fileheader" {
+		return 0
+	}
+	part, _ := content.Int64("part")
+	return int(part)
+}
+
+// ListSessions groups the journal files in dir into sessions, oldest
+// first. A file whose Fileheader part is greater than one continues
+// the preceding session.
+func ListSessions(dir string) ([]Session, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		if journalRE.MatchString(strings.ToLower(file.Name())) {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return lessJournalName(names[i], names[j]) })
+
+	var sessions []Session
+	for _, name := range names {
+		filename := filepath.Join(dir, name)
+		if len(sessions) > 0 && journalPart(filename) > 1 {
+			s := &sessions[len(sessions)-1]
+			s.Parts = append(s.Parts, filename)
+			continue
+		}
+		sessions = append(sessions, Session{
+			ID:    SessionID(name),
+			Parts: []string{filename},
+		})
+	}
+	return sessions, nil
+}
+
+// SessionReader reads the parts of a session as a single stream of
+// journal events. The Continued event at the end of a part and the
+// Fileheader at the start of the next one are dropped.
+type SessionReader struct {
+	session Session
+	part    int
+	file    *os.File
+	reader  *bufio.Reader
+}
+
+func OpenSession(s Session) *SessionReader {
+	return &SessionReader{session: s, part: -1}
+}
+
+// Next returns the next event of the session, or io.EOF at the end of
+// the last part. Blank lines are skipped.
+func (r *SessionReader) Next() (Json, error) {
+	for {
+		if r.reader == nil {
+			if err := r.nextPart(); err != nil {
+				return nil, err
+			}
+		}
+
+		line, err := r.reader.ReadBytes('\n')
+		if err == io.EOF {
+			r.closePart()
+		} else if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		content, err := ParseJournalLine(line)
+		if err != nil {
+			return nil, err
+		}
+		switch GetEventName(content) {
+		case "Continued":
+			continue
+		case "Fileheader":
+			if r.part > 0 {
+				continue
+			}
+		}
+		return content, nil
+	}
+}
+
+func (r *SessionReader) nextPart() error {
+	if r.part+1 >= len(r.session.Parts) {
+		return io.EOF
+	}
+	r.part++
+	f, err := os.Open(r.session.Parts[r.part])
+	if err != nil {
+		return err
+	}
+	r.file = f
+	r.reader = bufio.NewReader(f)
+	return nil
+}
+
+func (r *SessionReader) closePart() {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	r.reader = nil
+}
+
+func (r *SessionReader) Close() error {
+	r.closePart()
+	r.part = len(r.session.Parts)
+	return nil
+}
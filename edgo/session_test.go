@@ -0,0 +1,86 @@
+package edgo
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestSessionReader(t *testing.T) {
+	dir := t.TempDir()
+	writeJournal(t, dir, "Journal.2025-01-01T120000.01.log",
+		`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Fileheader", "part":1 }`,
+		`{ "timestamp":"2025-01-01T12:00:01Z", "event":"LoadGame" }`,
+		``,
+		"  \t\r",
+		`{ "timestamp":"2025-01-01T12:00:02Z", "event":"Docked" }`,
+		`{ "timestamp":"2025-01-01T12:00:03Z", "event":"Continued", "Part":2 }`)
+	writeJournal(t, dir, "Journal.2025-01-01T130000.02.log",
+		`{ "timestamp":"2025-01-01T13:00:00Z", "event":"Fileheader", "part":2 }`,
+		"\r",
+		`{ "timestamp":"2025-01-01T13:00:01Z", "event":"Undocked" }`,
+		``)
+
+	sessions, err := ListSessions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || len(sessions[0].Parts) != 2 {
+		t.Fatalf("sessions %+v", sessions)
+	}
+
+	r := OpenSession(sessions[0])
+	defer r.Close()
+	var got []string
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, GetEventName(e))
+	}
+	want := []string{"Fileheader", "LoadGame", "Docked", "Undocked"}
+	if len(got) != len(want) {
+		t.Fatalf("events %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("events %v, want %v", got, want)
+		}
+	}
+}
+
+func TestWatcherStartsInContinuedSession(t *testing.T) {
+	dir := t.TempDir()
+	writeJournal(t, dir, "Journal.2025-01-01T120000.01.log",
+		`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Fileheader", "part":1 }`,
+		`{ "timestamp":"2025-01-01T12:59:59Z", "event":"Continued", "Part":2 }`)
+	writeJournal(t, dir, "Journal.2025-01-01T130000.02.log",
+		`{ "timestamp":"2025-01-01T13:00:00Z", "event":"Fileheader", "part":2 }`,
+		`{ "timestamp":"2025-01-01T13:00:01Z", "event":"Shutdown" }`)
+	sessions, err := ListSessions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ew := runWatcher(t, dir, "Session*")
+	var got []string
+	for len(got) < 2 {
+		select {
+		case e := <-ew.Journals:
+			if s, ok := e.(*SessionEvent); ok {
+				got = append(got, s.Event)
+				if s.SessionID != sessions[0].ID {
+					t.Errorf("%s for session %q, want %q", s.Event, s.SessionID, sessions[0].ID)
+				}
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %v", got)
+		}
+	}
+	if got[0] != "SessionContinued" || got[1] != "SessionEnd" {
+		t.Errorf("events %v, want SessionContinued and SessionEnd", got)
+	}
+}
@@ -0,0 +1,53 @@
+package edgo
+
+import (
+	"context"
+	"sync"
+)
+
+// SourcedEvent is an event tagged with the watcher it came from. An
+// EliteWatcher with a Source sends its events wrapped in one, so that
+// the events of several watchers can be told apart; see MergeJournals.
+type SourcedEvent struct {
+	Source    string
+	Commander string // from the latest Commander or LoadGame event; empty before the first
+	Event     interface{}
+}
+
+// CommanderName returns the commander named by a Commander or LoadGame
+// event.
+func CommanderName(e Json) (string, bool) {
+	switch GetEventName(e) {
+	case "Commander":
+		return e.String("Name")
+	case "LoadGame":
+		return e.String("Commander")
+	}
+	return "", false
+}
+
+// MergeJournals sends the events of several watchers to one channel,
+// which is closed once all of their Journals are closed or ctx is done.
+// Give each watcher a Source to tell their events apart.
+func MergeJournals(ctx context.Context, watchers ...*EliteWatcher) <-chan interface{} {
+	out := make(chan interface{}, 10)
+	var wg sync.WaitGroup
+	for _, w := range watchers {
+		wg.Add(1)
+		go func(events <-chan interface{}) {
+			defer wg.Done()
+			for e := range events {
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(w.Journals)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
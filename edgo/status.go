@@ -1,36 +1,52 @@
 package edgo
 
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
 type Base struct {
 	RAW       string `json:"-"`
 	Timestamp string `json:"timestamp"`
 	Event     string `json:"event"`
 }
 
+// EventBase returns the common event fields of any type embedding Base.
+func (b *Base) EventBase() *Base {
+	return b
+}
+
+type eventBase interface {
+	EventBase() *Base
+}
+
 type Name_Localized struct {
 	Name          string `json:"Name"`
-	NameLocalised string `json:"Name_Localised,omitempty"`
+	NameLocalised string `json:"Name_Localised,omitempty" edgo:"optional"`
 }
 
 type Category_Localized struct {
 	Category          string `json:"Category"`
-	CategoryLocalised string `json:"Category_Localised,omitempty"`
+	CategoryLocalised string `json:"Category_Localised,omitempty" edgo:"optional"`
 }
 
 type ShipType_Localized struct {
 	ShipType          string `json:"ShipType"`
-	ShipTypeLocalised string `json:"ShipType_Localised,omitempty"`
+	ShipTypeLocalised string `json:"ShipType_Localised,omitempty" edgo:"optional"`
 }
 
 // 3.1 cargo.json
 type Cargo struct {
 	Base
 	Vessel    string `json:"Vessel"` // Ship | SRV
+	Count     int64  `json:"Count"`
 	Inventory []struct {
 		Name_Localized
 		Count     int64 `json:"Count"`
-		Stolen    int64 `json:"Stolen,omitempty"`
-		MissionId int64 `json:"MissionID,omitempty"`
-	} `json:"Inventory,omitempty"`
+		Stolen    int64 `json:"Stolen,omitempty" edgo:"optional"`
+		MissionId int64 `json:"MissionID,omitempty" edgo:"optional"`
+	} `json:"Inventory,omitempty" edgo:"optional"`
 }
 
 // 8.17 market.json
@@ -55,7 +71,7 @@ type Market struct {
 		Consumer      bool
 		Producer      bool
 		Rare          bool
-	} `json:"MarketItem,omitempty"`
+	} `json:"Items,omitempty" edgo:"optional"`
 }
 
 // 11.28 modulesinfo.json
@@ -72,12 +88,14 @@ type ModulesInfo struct {
 // navroute.json
 type NavRoute struct {
 	Base
-	Route []struct {
-		StarSystem    string     `json:"StarSystem"`
-		SystemAddress int64      `json:"SystemAddress"`
-		StarPos       [3]float64 `json:"StarPos"`
-		StarClass     string     `json:"StarClass"`
-	} `json:"Route"`
+	Route []NavRouteEntry `json:"Route"`
+}
+
+type NavRouteEntry struct {
+	StarSystem    string     `json:"StarSystem"`
+	SystemAddress int64      `json:"SystemAddress"`
+	StarPos       [3]float64 `json:"StarPos"`
+	StarClass     string     `json:"StarClass"`
 }
 
 // 8.31 outfitting.json
@@ -110,22 +128,248 @@ type Shipyard struct {
 }
 
 // 12 status.json
+//
+// On foot the ship fields such as Fuel, Cargo and Pips are absent and
+// remain zero; use OnFoot to tell the difference. Fields the game
+// leaves out in some states are tagged edgo:"optional" for strict
+// validation.
 type Status struct {
 	Base
-	Flags        int     `json:"Flags"`
-	Pips         [3]int  `json:"Pips"`
-	FireGroup    int     `json:"FireGroup"`
-	GuiFocus     int     `json:"GuiFocus"`
-	Cargo        float64 `json:"Cargo"`
-	LegalState   string  `json:"LegalState"`
-	Latitude     float64 `json:"Latitude"`
-	Longitude    float64 `json:"Longitude"`
-	Altitude     float64 `json:"Altitude"`
-	Heading      int64   `json:"Heading"`
-	BodyName     string  `json:"BodyName,omitempty"`
-	PlanetRadius float64 `json:"PlanetRadius"`
-	Fuel         struct {
+	Flags                   int     `json:"Flags"`
+	Flags2                  int     `json:"Flags2,omitempty" edgo:"optional"`
+	Pips                    [3]int  `json:"Pips" edgo:"optional"`
+	FireGroup               int     `json:"FireGroup" edgo:"optional"`
+	GuiFocus                int     `json:"GuiFocus" edgo:"optional"`
+	Cargo                   float64 `json:"Cargo" edgo:"optional"`
+	LegalState              string  `json:"LegalState" edgo:"optional"`
+	Latitude                float64 `json:"Latitude" edgo:"optional"`
+	Longitude               float64 `json:"Longitude" edgo:"optional"`
+	Altitude                float64 `json:"Altitude" edgo:"optional"`
+	Heading                 int64   `json:"Heading" edgo:"optional"`
+	BodyName                string  `json:"BodyName,omitempty" edgo:"optional"`
+	PlanetRadius            float64 `json:"PlanetRadius" edgo:"optional"`
+	Oxygen                  float64 `json:"Oxygen,omitempty" edgo:"optional"`      // 0.0 - 1.0
+	Health                  float64 `json:"Health,omitempty" edgo:"optional"`      // 0.0 - 1.0
+	Temperature             float64 `json:"Temperature,omitempty" edgo:"optional"` // kelvin
+	SelectedWeapon          string  `json:"SelectedWeapon,omitempty" edgo:"optional"`
+	SelectedWeaponLocalised string  `json:"SelectedWeapon_Localised,omitempty" edgo:"optional"`
+	Gravity                 float64 `json:"Gravity,omitempty" edgo:"optional"` // g
+	Balance                 int64   `json:"Balance,omitempty" edgo:"optional"`
+	Destination             *struct {
+		System int64  `json:"System"`
+		Body   int64  `json:"Body"`
+		Name   string `json:"Name"`
+	} `json:"Destination,omitempty" edgo:"optional"`
+	Fuel struct {
 		FuelMain      float64 `json:"FuelMain"`
 		FuelReservoir float64 `json:"FuelReservoir"`
-	} `json:"Fuel"`
+	} `json:"Fuel" edgo:"optional"`
+}
+
+// Status.Flags bits.
+const (
+	FlagDocked = 1 << iota
+	FlagLanded
+	FlagLandingGearDown
+	FlagShieldsUp
+	FlagSupercruise
+	FlagFlightAssistOff
+	FlagHardpointsDeployed
+	FlagInWing
+	FlagLightsOn
+	FlagCargoScoopDeployed
+	FlagSilentRunning
+	FlagScoopingFuel
+	FlagSrvHandbrake
+	FlagSrvTurretView
+	FlagSrvTurretRetracted
+	FlagSrvDriveAssist
+	FlagFsdMassLocked
+	FlagFsdCharging
+	FlagFsdCooldown
+	FlagLowFuel
+	FlagOverHeating
+	FlagHasLatLong
+	FlagIsInDanger
+	FlagBeingInterdicted
+	FlagInMainShip
+	FlagInFighter
+	FlagInSRV
+	FlagHudInAnalysisMode
+	FlagNightVision
+	FlagAltitudeFromAverageRadius
+	FlagFsdJump
+	FlagSrvHighBeam
+)
+
+// Status.Flags2 bits.
+const (
+	Flag2OnFoot = 1 << iota
+	Flag2InTaxi
+	Flag2InMulticrew
+	Flag2OnFootInStation
+	Flag2OnFootOnPlanet
+	Flag2AimDownSight
+	Flag2LowOxygen
+	Flag2LowHealth
+	Flag2Cold
+	Flag2Hot
+	Flag2VeryCold
+	Flag2VeryHot
+	Flag2GlideMode
+	Flag2OnFootInHangar
+	Flag2OnFootSocialSpace
+	Flag2OnFootExterior
+	Flag2BreathableAtmosphere
+	Flag2TelepresenceMulticrew
+	Flag2PhysicalMulticrew
+	Flag2FsdHyperdriveCharging
+)
+
+// OnFoot reports whether the commander is on foot, in which case the
+// ship fields are not present.
+func (s *Status) OnFoot() bool {
+	return s.Flags2&Flag2OnFoot != 0
+}
+
+// ShipFuel returns the fuel in the main tank and the reservoir, in
+// tons. On foot the game leaves Fuel out, and ok is false rather than
+// reporting empty tanks.
+func (s *Status) ShipFuel() (main, reservoir float64, ok bool) {
+	if s.OnFoot() {
+		return 0, 0, false
+	}
+	return s.Fuel.FuelMain, s.Fuel.FuelReservoir, true
+}
+
+// StatusChange is sent by EliteWatcher when one of the on foot,
+// destination or fuel reservoir fields of status.json changes. Event is
+// "Status" followed by the field name, for example "StatusOxygen" or
+// "StatusDestination".
+//
+// For filter predicates, the new value is also given under the field
+// name, so that StatusDestination:Destination.Name=Sol matches a
+// destination being set to Sol.
+type StatusChange struct {
+	Base
+	Field string
+	Old   interface{}
+	New   interface{}
+}
+
+// StatusChanges compares the on foot, destination and fuel reservoir
+// fields of two status files. The first status read, when prev is nil,
+// has nothing to be compared with and gives no changes; the status
+// itself holds the values. FuelReservoir is only compared while in the
+// ship, as on foot the game leaves the fuel out.
+func StatusChanges(prev, cur *Status) []*StatusChange {
+	if prev == nil {
+		return nil
+	}
+	var changes []*StatusChange
+	add := func(field string, old, new interface{}) {
+		if reflect.DeepEqual(old, new) {
+			return
+		}
+		changes = append(changes, &StatusChange{
+			Base:  Base{Timestamp: cur.Timestamp, Event: "Status" + field},
+			Field: field,
+			Old:   old,
+			New:   new,
+		})
+	}
+	add("Flags2", prev.Flags2, cur.Flags2)
+	add("Oxygen", prev.Oxygen, cur.Oxygen)
+	add("Health", prev.Health, cur.Health)
+	add("Temperature", prev.Temperature, cur.Temperature)
+	add("SelectedWeapon", prev.SelectedWeapon, cur.SelectedWeapon)
+	add("Gravity", prev.Gravity, cur.Gravity)
+	add("Balance", prev.Balance, cur.Balance)
+	add("Destination", prev.Destination, cur.Destination)
+	_, old, prevOK := prev.ShipFuel()
+	_, new, curOK := cur.ShipFuel()
+	if prevOK && curOK {
+		add("FuelReservoir", old, new)
+	}
+	return changes
+}
+
+// fields returns the change as Json, for filter predicates. Old and New
+// are converted as if decoded from the journal, so that predicates can
+// look into the destination.
+func (c *StatusChange) fields() (Json, error) {
+	old, err := jsonValue(c.Old)
+	if err != nil {
+		return nil, err
+	}
+	new, err := jsonValue(c.New)
+	if err != nil {
+		return nil, err
+	}
+	return Json{
+		"timestamp": c.Timestamp,
+		"event":     c.Event,
+		"Field":     c.Field,
+		"Old":       old,
+		"New":       new,
+		c.Field:     new,
+	}, nil
+}
+
+// jsonValue returns v as encoding it to JSON and decoding it into Json
+// would.
+func jsonValue(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var value interface{}
+	err = dec.Decode(&value)
+	return value, err
+}
+
+// MicroResource is an Odyssey item, component, consumable or data
+// entry held in the ship locker or backpack.
+type MicroResource struct {
+	Name_Localized
+	OwnerID   int64 `json:"OwnerID"`
+	MissionID int64 `json:"MissionID,omitempty" edgo:"optional"`
+	Count     int64 `json:"Count"`
+}
+
+// MicroResources is the inventory shared by the ship locker and backpack.
+type MicroResources struct {
+	Items       []MicroResource `json:"Items,omitempty" edgo:"optional"`
+	Components  []MicroResource `json:"Components,omitempty" edgo:"optional"`
+	Consumables []MicroResource `json:"Consumables,omitempty" edgo:"optional"`
+	Data        []MicroResource `json:"Data,omitempty" edgo:"optional"`
+}
+
+// shiplocker.json
+type ShipLocker struct {
+	Base
+	MicroResources
+}
+
+// backpack.json
+type Backpack struct {
+	Base
+	MicroResources
+}
+
+// fcmaterials.json
+type FCMaterials struct {
+	Base
+	MarketID    int64  `json:"MarketID"`
+	CarrierName string `json:"CarrierName"`
+	CarrierID   string `json:"CarrierID"`
+	Items       []struct {
+		ID int64 `json:"id"`
+		Name_Localized
+		Price  int64 `json:"Price"`
+		Stock  int64 `json:"Stock"`
+		Demand int64 `json:"Demand"`
+	} `json:"Items,omitempty" edgo:"optional"`
 }
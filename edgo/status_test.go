@@ -0,0 +1,154 @@
+package edgo
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestStatusChanges(t *testing.T) {
+	ship := statusEvent(t, `{ "timestamp":"2025-01-01T12:00:00Z", "event":"Status", "Flags":16, "Fuel":{ "FuelMain":16, "FuelReservoir":0.5 } }`)
+	burnt := statusEvent(t, `{ "timestamp":"2025-01-01T12:00:01Z", "event":"Status", "Flags":16, "Fuel":{ "FuelMain":16, "FuelReservoir":0.25 }, "Destination":{ "System":10477373803, "Body":0, "Name":"Sol" } }`)
+	onFoot := statusEvent(t, `{ "timestamp":"2025-01-01T12:00:02Z", "event":"Status", "Flags":0, "Flags2":17, "Oxygen":1, "Health":1, "Temperature":293.5, "SelectedWeapon":"$humanoid_fists_name;", "Gravity":0.17, "Balance":1000 }`)
+	hurt := statusEvent(t, `{ "timestamp":"2025-01-01T12:00:03Z", "event":"Status", "Flags":0, "Flags2":17, "Oxygen":0.4, "Health":1, "Temperature":293.5, "SelectedWeapon":"$humanoid_fists_name;", "Gravity":0.17, "Balance":1000 }`)
+
+	tests := []struct {
+		name      string
+		prev, cur *Status
+		want      string
+	}{
+		{"first read", nil, onFoot, "[]"},
+		{"unchanged", ship, ship, "[]"},
+		{"reservoir and destination", ship, burnt,
+			"[StatusDestination:<nil>->&{10477373803 0 Sol} StatusFuelReservoir:0.5->0.25]"},
+		{"on foot", burnt, onFoot,
+			"[StatusFlags2:0->17 StatusOxygen:0->1 StatusHealth:0->1 StatusTemperature:0->293.5 " +
+				"StatusSelectedWeapon:->$humanoid_fists_name; StatusGravity:0->0.17 StatusBalance:0->1000 " +
+				"StatusDestination:&{10477373803 0 Sol}-><nil>]"},
+		{"oxygen", onFoot, hurt, "[StatusOxygen:1->0.4]"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, c := range StatusChanges(tt.prev, tt.cur) {
+			if c.Timestamp != tt.cur.Timestamp || c.Event != "Status"+c.Field {
+				t.Errorf("%s: change %+v", tt.name, c)
+			}
+			got = append(got, fmt.Sprintf("%s:%v->%v", c.Event, c.Old, c.New))
+		}
+		if s := fmt.Sprint(got); s != tt.want {
+			t.Errorf("%s: changes\n%s\nwant\n%s", tt.name, s, tt.want)
+		}
+	}
+}
+
+func TestStatusShipFuel(t *testing.T) {
+	st := statusEvent(t, `{ "timestamp":"2025-01-01T12:00:00Z", "event":"Status", "Flags":16, "Fuel":{ "FuelMain":16, "FuelReservoir":0.5 } }`)
+	if main, reservoir, ok := st.ShipFuel(); !ok || main != 16 || reservoir != 0.5 {
+		t.Errorf("ShipFuel = %v, %v, %v", main, reservoir, ok)
+	}
+	st = statusEvent(t, `{ "timestamp":"2025-01-01T12:00:00Z", "event":"Status", "Flags":0, "Flags2":1 }`)
+	if _, _, ok := st.ShipFuel(); ok {
+		t.Error("ShipFuel on foot")
+	}
+}
+
+func TestStatusChangeFilter(t *testing.T) {
+	prev := statusEvent(t, `{ "timestamp":"2025-01-01T12:00:00Z", "event":"Status", "Flags":16 }`)
+	cur := statusEvent(t, `{ "timestamp":"2025-01-01T12:00:01Z", "event":"Status", "Flags":16, "Destination":{ "System":10477373803, "Body":3, "Name":"Earth" } }`)
+	changes := StatusChanges(prev, cur)
+	if len(changes) != 1 {
+		t.Fatalf("changes %v", changes)
+	}
+	content, err := changes[0].fields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		pattern string
+		match   bool
+	}{
+		{"StatusDestination", true},
+		{"StatusDestination:Destination.Name=Earth", true},
+		{"StatusDestination:Destination.Name=Sol", false},
+		{"StatusDestination:New.System=10477373803", true},
+		{"StatusDestination:Destination.Body=3", true},
+		{"StatusDestination:Old=null", true},
+		{"StatusDestination:Old.Name=Earth", false},
+	}
+	for _, tt := range tests {
+		f, err := CompileFilter([]string{tt.pattern}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.Match(changes[0].Event, content); got != tt.match {
+			t.Errorf("%s matches %v, want %v", tt.pattern, got, tt.match)
+		}
+	}
+}
+
+func TestParseMicroResources(t *testing.T) {
+	const shipLocker = `{ "timestamp":"2025-01-01T12:00:00Z", "event":"ShipLocker", "Items":[ { "Name":"healthpack", "Name_Localised":"Medkit", "OwnerID":0, "Count":2 } ], "Components":[ { "Name":"graphene", "OwnerID":0, "Count":7 } ], "Consumables":[ ], "Data":[ { "Name":"surveilleancelogs", "Name_Localised":"Surveillance Logs", "OwnerID":0, "MissionID":987654321012, "Count":1 } ] }`
+	const backpack = `{ "timestamp":"2025-01-01T12:00:01Z", "event":"Backpack", "Items":[ ], "Components":[ ], "Consumables":[ { "Name":"energycell", "Name_Localised":"Energy Cell", "OwnerID":0, "Count":3 } ], "Data":[ ] }`
+
+	v, err := ParseStatusContents("ShipLocker.json", []byte(shipLocker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	locker, ok := v.(*ShipLocker)
+	if !ok {
+		t.Fatalf("ShipLocker.json decoded as %T", v)
+	}
+	if len(locker.Items) != 1 || locker.Items[0].Name != "healthpack" || locker.Items[0].NameLocalised != "Medkit" || locker.Items[0].Count != 2 {
+		t.Errorf("Items = %+v", locker.Items)
+	}
+	if len(locker.Components) != 1 || locker.Components[0].Count != 7 {
+		t.Errorf("Components = %+v", locker.Components)
+	}
+	if len(locker.Data) != 1 || locker.Data[0].MissionID != 987654321012 {
+		t.Errorf("Data = %+v", locker.Data)
+	}
+
+	v, err = ParseStatusContents("Backpack.json", []byte(backpack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack, ok := v.(*Backpack)
+	if !ok {
+		t.Fatalf("Backpack.json decoded as %T", v)
+	}
+	if pack.Event != "Backpack" || pack.Timestamp != "2025-01-01T12:00:01Z" {
+		t.Errorf("Base = %+v", pack.Base)
+	}
+	if len(pack.Items) != 0 || len(pack.Consumables) != 1 || pack.Consumables[0].Name != "energycell" || pack.Consumables[0].Count != 3 {
+		t.Errorf("Consumables = %+v", pack.Consumables)
+	}
+}
+
+func TestParseFCMaterials(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+		items   int
+	}{
+		{"stocked", `{ "timestamp":"2025-01-01T12:00:00Z", "event":"FCMaterials", "MarketID":3700000000, "CarrierName":"NEW HORIZON", "CarrierID":"X9Z-1QT", "Items":[ { "id":128961524, "Name":"$aerogel_name;", "Name_Localised":"Aerogel", "Price":500, "Stock":0, "Demand":10 }, { "id":128961525, "Name":"$chemicalcatalyst_name;", "Name_Localised":"Chemical Catalyst", "Price":1000, "Stock":6, "Demand":0 } ] }`, 2},
+		// Items is optional, and the event name comes from the
+		// registry when the file has none.
+		{"empty", `{ "timestamp":"2025-01-01T12:00:00Z", "MarketID":3700000000, "CarrierName":"NEW HORIZON", "CarrierID":"X9Z-1QT" }`, 0},
+	} {
+		v, err := ParseStatusContents("FCMaterials.json", []byte(tt.content))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		fc, ok := v.(*FCMaterials)
+		if !ok {
+			t.Fatalf("%s: decoded as %T", tt.name, v)
+		}
+		if fc.Event != "FCMaterials" || fc.MarketID != 3700000000 || fc.CarrierID != "X9Z-1QT" || fc.CarrierName != "NEW HORIZON" {
+			t.Errorf("%s: %+v", tt.name, fc)
+		}
+		if len(fc.Items) != tt.items {
+			t.Errorf("%s: %d items, want %d", tt.name, len(fc.Items), tt.items)
+		} else if tt.items > 0 && (fc.Items[1].ID != 128961525 || fc.Items[1].NameLocalised != "Chemical Catalyst" || fc.Items[1].Stock != 6 || fc.Items[0].Demand != 10) {
+			t.Errorf("%s: items %+v", tt.name, fc.Items)
+		}
+	}
+}
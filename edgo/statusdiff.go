@@ -0,0 +1,37 @@
+package edgo
+
+import (
+	"reflect"
+	"sort"
+)
+
+// StatusDiff is sent instead of a full status file when
+// EliteWatcher.StatusDiffs is set. It holds only the top level fields
+// that changed since the file was last sent.
+type StatusDiff struct {
+	Base
+	Filename string
+	Changed  Json     // new values of changed and added fields
+	Removed  []string // fields that are no longer present
+}
+
+// DiffStatus compares two decoded status files. The timestamp and
+// event fields are ignored.
+func DiffStatus(prev, cur Json) (changed Json, removed []string) {
+	changed = make(Json)
+	for k, v := range cur {
+		if k == "timestamp" || k == "event" {
+			continue
+		}
+		if old, ok := prev[k]; !ok || !reflect.DeepEqual(old, v) {
+			changed[k] = v
+		}
+	}
+	for k := range prev {
+		if _, ok := cur[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(removed)
+	return changed, removed
+}
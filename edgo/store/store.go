@@ -0,0 +1,290 @@
+// Package store is a small embedded store for journal events, indexed
+// by event name, time, system, station and ship.
+//
+// A store is a directory holding an append-only events.jsonl data file
+// and an index.gob file that is rewritten when the store is closed.
+package store
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var ErrClosed = errors.New("store: closed")
+
+// Record is an indexed journal event.
+type Record struct {
+	Time    string          `json:"time"`
+	Event   string          `json:"event"`
+	System  string          `json:"system,omitempty"`
+	Station string          `json:"station,omitempty"`
+	Ship    string          `json:"ship,omitempty"`
+	File    string          `json:"file"`
+	Line    int             `json:"line"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Query selects records. Empty fields match everything; string fields
+// are compared case insensitively. Since is inclusive and Until is
+// exclusive, both compared against the RFC 3339 record time.
+type Query struct {
+	Event   string
+	System  string
+	Station string
+	Ship    string
+	Since   string
+	Until   string
+	Limit   int
+}
+
+// index is the on-disk index. Postings hold record numbers in
+// insertion order.
+type index struct {
+	Offsets  []int64
+	Times    []string
+	Event    map[string][]int32
+	System   map[string][]int32
+	Station  map[string][]int32
+	Ship     map[string][]int32
+	Files    map[string]int // journal file -> lines indexed
+	DataSize int64
+}
+
+type Store struct {
+	dir    string
+	data   *os.File
+	writer *bufio.Writer
+	index  index
+}
+
+// Open opens or creates the store in dir.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &Store{
+		dir: dir,
+		index: index{
+			Event:   make(map[string][]int32),
+			System:  make(map[string][]int32),
+			Station: make(map[string][]int32),
+			Ship:    make(map[string][]int32),
+			Files:   make(map[string]int),
+		},
+	}
+
+	if f, err := os.Open(filepath.Join(dir, "index.gob")); err == nil {
+		err = gob.NewDecoder(f).Decode(&s.index)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	data, err := os.OpenFile(filepath.Join(dir, "events.jsonl"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// Drop anything written after the index was last saved.
+	if err := data.Truncate(s.index.DataSize); err != nil {
+		data.Close()
+		return nil, err
+	}
+	if _, err := data.Seek(s.index.DataSize, io.SeekStart); err != nil {
+		data.Close()
+		return nil, err
+	}
+	s.data = data
+	s.writer = bufio.NewWriter(data)
+	return s, nil
+}
+
+// Close writes the index and closes the store.
+func (s *Store) Close() error {
+	if s.data == nil {
+		return ErrClosed
+	}
+	err := s.Flush()
+	if cerr := s.data.Close(); err == nil {
+		err = cerr
+	}
+	s.data = nil
+	return err
+}
+
+// Flush writes pending records and the index to disk.
+func (s *Store) Flush() error {
+	if s.data == nil {
+		return ErrClosed
+	}
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	if err := s.data.Sync(); err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(s.dir, "index.gob.tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(&s.index); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, "index.gob"))
+}
+
+// Len returns the number of records.
+func (s *Store) Len() int {
+	return len(s.index.Offsets)
+}
+
+// Indexed returns the number of lines of a journal file already added.
+func (s *Store) Indexed(file string) int {
+	return s.index.Files[filepath.Base(file)]
+}
+
+// SetIndexed records the number of lines of a journal file added.
+func (s *Store) SetIndexed(file string, lines int) {
+	s.index.Files[filepath.Base(file)] = lines
+}
+
+// Add appends a record to the store.
+func (s *Store) Add(r *Record) error {
+	if s.data == nil {
+		return ErrClosed
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if _, err := s.writer.Write(b); err != nil {
+		return err
+	}
+
+	n := int32(len(s.index.Offsets))
+	s.index.Offsets = append(s.index.Offsets, s.index.DataSize)
+	s.index.Times = append(s.index.Times, r.Time)
+	s.index.DataSize += int64(len(b))
+	addPosting(s.index.Event, r.Event, n)
+	addPosting(s.index.System, r.System, n)
+	addPosting(s.index.Station, r.Station, n)
+	addPosting(s.index.Ship, r.Ship, n)
+	return nil
+}
+
+func addPosting(m map[string][]int32, key string, n int32) {
+	if key != "" {
+		key = strings.ToLower(key)
+		m[key] = append(m[key], n)
+	}
+}
+
+// Query returns the matching records, sorted by time.
+func (s *Store) Query(q Query) ([]*Record, error) {
+	if s.data == nil {
+		return nil, ErrClosed
+	}
+	if err := s.writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	var lists [][]int32
+	for _, p := range []struct {
+		m   map[string][]int32
+		key string
+	}{
+		{s.index.Event, q.Event},
+		{s.index.System, q.System},
+		{s.index.Station, q.Station},
+		{s.index.Ship, q.Ship},
+	} {
+		if p.key != "" {
+			lists = append(lists, p.m[strings.ToLower(p.key)])
+		}
+	}
+
+	var matches []int32
+	if len(lists) == 0 {
+		matches = make([]int32, len(s.index.Offsets))
+		for i := range matches {
+			matches[i] = int32(i)
+		}
+	} else {
+		matches = lists[0]
+		for _, l := range lists[1:] {
+			matches = intersect(matches, l)
+		}
+	}
+
+	var selected []int32
+	for _, n := range matches {
+		t := s.index.Times[n]
+		if (q.Since == "" || t >= q.Since) && (q.Until == "" || t < q.Until) {
+			selected = append(selected, n)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return s.index.Times[selected[i]] < s.index.Times[selected[j]]
+	})
+	if q.Limit > 0 && len(selected) > q.Limit {
+		selected = selected[:q.Limit]
+	}
+
+	result := make([]*Record, 0, len(selected))
+	for _, n := range selected {
+		r, err := s.read(n)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func (s *Store) read(n int32) (*Record, error) {
+	end := s.index.DataSize
+	if int(n)+1 < len(s.index.Offsets) {
+		end = s.index.Offsets[n+1]
+	}
+	b := make([]byte, end-s.index.Offsets[n])
+	if _, err := s.data.ReadAt(b, s.index.Offsets[n]); err != nil {
+		return nil, err
+	}
+	r := &Record{}
+	err := json.Unmarshal(b, r)
+	return r, err
+}
+
+// intersect returns the record numbers present in both sorted lists.
+func intersect(a, b []int32) []int32 {
+	var result []int32
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+)
+
+func record(time, event, system string) *Record {
+	return &Record{
+		Time:   time,
+		Event:  event,
+		System: system,
+		File:   "Journal.2025-01-01T120000.01.log",
+		Data:   []byte(fmt.Sprintf(`{"timestamp":%q,"event":%q}`, time, event)),
+	}
+}
+
+func times(records []*Record) string {
+	var s []string
+	for _, r := range records {
+		s = append(s, r.Time[11:16]+" "+r.Event)
+	}
+	return fmt.Sprint(s)
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Added out of time order, as several journal files may be.
+	for _, r := range []*Record{
+		record("2025-01-01T12:00:00Z", "FSDJump", "Sol"),
+		record("2025-01-01T12:10:00Z", "Docked", "Sol"),
+		record("2025-01-01T11:00:00Z", "FSDJump", "Alpha Centauri"),
+		record("2025-01-01T12:20:00Z", "FSDJump", "Barnard's Star"),
+	} {
+		if err := s.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.SetIndexed("/journals/Journal.2025-01-01T120000.01.log", 4)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != ErrClosed {
+		t.Errorf("second Close: %v", err)
+	}
+
+	if s, err = Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Len() != 4 {
+		t.Errorf("reopened store has %d records", s.Len())
+	}
+	if n := s.Indexed("Journal.2025-01-01T120000.01.log"); n != 4 {
+		t.Errorf("Indexed = %d", n)
+	}
+	if err := s.Add(record("2025-01-01T12:30:00Z", "Undocked", "Sol")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		q    Query
+		want string
+	}{
+		{Query{}, "[11:00 FSDJump 12:00 FSDJump 12:10 Docked 12:20 FSDJump 12:30 Undocked]"},
+		{Query{Event: "fsdjump"}, "[11:00 FSDJump 12:00 FSDJump 12:20 FSDJump]"},
+		{Query{System: "SOL"}, "[12:00 FSDJump 12:10 Docked 12:30 Undocked]"},
+		{Query{Event: "FSDJump", System: "Sol"}, "[12:00 FSDJump]"},
+		{Query{Event: "Scan"}, "[]"},
+		{Query{Since: "2025-01-01T12:00:00Z"}, "[12:00 FSDJump 12:10 Docked 12:20 FSDJump 12:30 Undocked]"},
+		{Query{Until: "2025-01-01T12:10:00Z"}, "[11:00 FSDJump 12:00 FSDJump]"},
+		{Query{Since: "2025-01-01T12:00:00Z", Until: "2025-01-01T12:20:00Z"}, "[12:00 FSDJump 12:10 Docked]"},
+		{Query{Event: "FSDJump", Since: "2025-01-01T11:30:00Z"}, "[12:00 FSDJump 12:20 FSDJump]"},
+		{Query{Limit: 2}, "[11:00 FSDJump 12:00 FSDJump]"},
+	}
+	for _, tt := range tests {
+		records, err := s.Query(tt.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := times(records); got != tt.want {
+			t.Errorf("Query(%+v) = %s, want %s", tt.q, got, tt.want)
+		}
+	}
+
+	records, _ := s.Query(Query{Event: "Docked"})
+	if len(records) != 1 || string(records[0].Data) != `{"timestamp":"2025-01-01T12:10:00Z","event":"Docked"}` {
+		t.Errorf("Docked record %+v", records)
+	}
+}
+
+func TestStoreDropsUnindexedRecords(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add(record("2025-01-01T12:00:00Z", "FSDJump", "Sol"))
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// Written to the data file, but not to the index, as if the
+	// indexer was killed.
+	s.Add(record("2025-01-01T12:10:00Z", "Docked", "Sol"))
+	s.writer.Flush()
+	s.data.Close()
+
+	if s, err = Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Add(record("2025-01-01T12:20:00Z", "Undocked", "Sol"))
+	records, err := s.Query(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := times(records), "[12:00 FSDJump 12:20 Undocked]"; got != want {
+		t.Errorf("records %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+package edgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Kinds of ValidationWarning.
+const (
+	UnknownField = "unknown"
+	MissingField = "missing"
+)
+
+// ValidationWarning reports a field of a status file that does not match
+// the struct it is decoded into.
+type ValidationWarning struct {
+	File string `json:"file"`
+	Path string `json:"path"` // as in FieldSchema, e.g. "Items[].Name"
+	Kind string `json:"kind"` // UnknownField or MissingField
+}
+
+func (w ValidationWarning) String() string {
+	return w.File + ": " + w.Kind + " field " + w.Path
+}
+
+// ParseStatusContentsStrict parses a status file like ParseStatusContents
+// and also checks it against the registered struct, in the manner of
+// json.Decoder.DisallowUnknownFields but reporting every field rather
+// than stopping at the first. Fields the struct does not declare are
+// reported as unknown, and absent fields are reported as missing unless
+// they are tagged edgo:"optional". Files registered without a struct have
+// nothing to check.
+func ParseStatusContentsStrict(filename string, content []byte) (interface{}, []ValidationWarning, error) {
+	obj, err := ParseStatusContents(filename, content)
+	if err != nil {
+		return obj, nil, err
+	}
+	sf, _ := LookupStatusFile(filename)
+	if sf.New == nil {
+		return obj, nil, nil
+	}
+
+	var raw interface{}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return obj, nil, err
+	}
+	v := &validator{file: filepath.Base(filename)}
+	v.check(reflect.TypeOf(sf.New()), raw, "")
+	sort.Slice(v.warnings, func(i, j int) bool {
+		return v.warnings[i].Path < v.warnings[j].Path
+	})
+	return obj, v.warnings, nil
+}
+
+type validator struct {
+	file     string
+	warnings []ValidationWarning
+	reported map[ValidationWarning]bool
+}
+
+// warn records a warning, once per path for all the elements of arrays.
+func (v *validator) warn(path, kind string) {
+	w := ValidationWarning{File: v.file, Path: path, Kind: kind}
+	if v.reported == nil {
+		v.reported = make(map[ValidationWarning]bool)
+	}
+	if !v.reported[w] {
+		v.reported[w] = true
+		v.warnings = append(v.warnings, w)
+	}
+}
+
+// check compares the value at path against type t.
+func (v *validator) check(t reflect.Type, value interface{}, path string) {
+	t = indirectType(t)
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		seen := make(map[string]bool)
+		for key, val := range obj {
+			f := lookupJSONField(fields, key)
+			if f == nil {
+				v.warn(joinPath(path, key), UnknownField)
+				continue
+			}
+			seen[f.name] = true
+			v.check(f.typ, val, joinPath(path, f.name))
+		}
+		for _, f := range fields {
+			if !f.optional && !seen[f.name] {
+				v.warn(joinPath(path, f.name), MissingField)
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		if arr, ok := value.([]interface{}); ok {
+			for _, elem := range arr {
+				v.check(t.Elem(), elem, path+"[]")
+			}
+		}
+	}
+}
+
+// lookupJSONField finds the field for a key the way encoding/json does,
+// preferring an exact match over a case-insensitive one.
+func lookupJSONField(fields []jsonField, key string) *jsonField {
+	var fold *jsonField
+	for i := range fields {
+		if fields[i].name == key {
+			return &fields[i]
+		}
+		if fold == nil && strings.EqualFold(fields[i].name, key) {
+			fold = &fields[i]
+		}
+	}
+	return fold
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,49 @@
+package edgo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseStatusContentsStrict(t *testing.T) {
+	// In space, the on foot and planetary fields are left out.
+	content := []byte(`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Status", "Flags":16842765, "Pips":[4,4,4], "FireGroup":0, "GuiFocus":0, "Fuel":{ "FuelMain":12.5, "FuelReservoir":0.5, "FuelExtra":1 }, "Cargo":0.0, "LegalState":"Clean", "Balance":1000, "NewThing":1 }`)
+	_, warnings, err := ParseStatusContentsStrict("Status.json", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ValidationWarning{
+		{"Status.json", "Fuel.FuelExtra", UnknownField},
+		{"Status.json", "NewThing", UnknownField},
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("warnings %v, want %v", warnings, want)
+	}
+	for i := range want {
+		if warnings[i] != want[i] {
+			t.Errorf("warning %d is %v, want %v", i, warnings[i], want[i])
+		}
+	}
+
+	_, warnings, err = ParseStatusContentsStrict("Status.json", []byte(`{ "timestamp":"2025-01-01T12:00:00Z", "event":"Status" }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Path != "Flags" || warnings[0].Kind != MissingField {
+		t.Errorf("warnings %v, want Flags missing", warnings)
+	}
+}
+
+func TestMarshalStatusKeepsZeros(t *testing.T) {
+	st := &Status{Flags: 1}
+	st.Event = "Status"
+	b, err := MarshalJournal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"FireGroup":0`, `"GuiFocus":0`, `"Pips":[0,0,0]`, `"Fuel":{ `} {
+		if !bytes.Contains(b, []byte(field)) {
+			t.Errorf("%s does not contain %s", b, field)
+		}
+	}
+}
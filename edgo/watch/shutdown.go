@@ -1,6 +1,7 @@
 package watch
 
 import (
+	"context"
 	"log"
 )
 
@@ -48,3 +49,42 @@ func (s *shutdownImpl) Kill(reason error) {
 func NewShutdown() Shutdown {
 	return &shutdownImpl{make(chan struct{}, 1)}
 }
+
+// WithShutdown returns a context that is cancelled when shutdown is
+// dying, for passing to the context based Run methods.
+func WithShutdown(parent context.Context, shutdown Shutdown) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	go func() {
+		select {
+		case <-shutdown.Dying():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+type contextShutdown struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (s *contextShutdown) Dying() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+func (s *contextShutdown) Kill(reason error) {
+	select {
+	case <-s.ctx.Done():
+		return
+	default:
+		log.Println("shutdown: ", reason)
+		s.cancel()
+	}
+}
+
+// ContextShutdown adapts a context to Shutdown, for code that has not
+// moved to contexts. Kill calls cancel.
+func ContextShutdown(ctx context.Context, cancel context.CancelFunc) Shutdown {
+	return &contextShutdown{ctx, cancel}
+}
@@ -2,6 +2,8 @@ package watch
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"log"
 	"path/filepath"
 	"sync"
@@ -50,6 +52,8 @@ func (op Op) String() string {
 	return buffer.String()[1:] // Strip leading pipe
 }
 
+var ErrWatcherClosed = errors.New("watch: watcher closed")
+
 // Event describes a file change that affects `Name`
 type Event struct {
 	Name string
@@ -66,6 +70,9 @@ type Watcher struct {
 	add      chan string
 	remove   chan string
 	err      chan error
+	closing  chan struct{} // closed by Close
+	done     chan struct{} // closed when Run returns
+	once     sync.Once
 }
 
 func MakeWatcher() *Watcher {
@@ -74,25 +81,41 @@ func MakeWatcher() *Watcher {
 		add:      make(chan string, 1),
 		remove:   make(chan string, 1),
 		err:      make(chan error, 1),
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
 		Events:   make(chan Event, 1),
 	}
 }
 
-// Close watcher related resources.
+// Close stops Run. Events is closed once Run has returned, so that
+// receivers can drain it.
 func (w *Watcher) Close() {
-	close(w.add)
-	close(w.remove)
-	close(w.err)
-	close(w.Events)
+	w.once.Do(func() {
+		close(w.closing)
+	})
 }
 
 // RunLoop is the main watcher run loop; typically this is
-// used inside a go routine.
+// used inside a go routine. It is kept for callers using Shutdown;
+// see Run.
 func (w *Watcher) RunLoop(shutdown Shutdown) {
+	ctx, cancel := WithShutdown(context.Background(), shutdown)
+	defer cancel()
+	if err := w.Run(ctx); err != nil {
+		shutdown.Kill(err)
+	}
+}
+
+// Run watches for changes until ctx is done or Close is called, and
+// then closes Events. It returns an error if the watch cannot be set
+// up. Run must only be called once.
+func (w *Watcher) Run(ctx context.Context) error {
+	defer close(w.Events)
+	defer close(w.done)
+
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		shutdown.Kill(err)
-		return
+		return err
 	}
 	defer watcher.Close()
 
@@ -112,18 +135,21 @@ func (w *Watcher) RunLoop(shutdown Shutdown) {
 
 		case err, ok = <-watcher.Errors:
 			if !ok {
-				return
+				return nil
 			}
 			log.Println("error:", err)
 			continue
 
 		case evt, ok = <-watcher.Events:
 			if !ok {
-				return
+				return nil
 			}
 
-		case <-shutdown.Dying():
-			return
+		case <-ctx.Done():
+			return nil
+
+		case <-w.closing:
+			return nil
 		}
 
 		absName, err := filepath.Abs(evt.Name)
@@ -149,8 +175,10 @@ func (w *Watcher) RunLoop(shutdown Shutdown) {
 		select {
 		case w.Events <- newevent:
 			/*noop*/
-		case <-shutdown.Dying():
-			return
+		case <-ctx.Done():
+			return nil
+		case <-w.closing:
+			return nil
 		}
 	}
 	panic("unreachable")
@@ -166,13 +194,20 @@ func (w *Watcher) AddWatch(fname string) error {
 	w.mux.Lock()
 	_, ok := w.watchset[fname]
 	if ok {
+		w.mux.Unlock()
 		return nil
 	}
 	w.watchset[fname] = struct{}{}
 	w.mux.Unlock()
 
-	w.add <- fname
-	return <-w.err
+	if err := w.request(w.add, fname); err != nil {
+		// Forget the watch, so that adding it can be retried.
+		w.mux.Lock()
+		delete(w.watchset, fname)
+		w.mux.Unlock()
+		return err
+	}
+	return nil
 }
 
 // RemoveWatch removes a watch from the watcher, unless it is not already present.
@@ -185,11 +220,26 @@ func (w *Watcher) RemoveWatch(fname string) error {
 	w.mux.Lock()
 	_, ok := w.watchset[fname]
 	if !ok {
+		w.mux.Unlock()
 		return nil
 	}
 	delete(w.watchset, fname)
 	w.mux.Unlock()
 
-	w.remove <- fname
-	return <-w.err
+	return w.request(w.remove, fname)
+}
+
+// request asks Run to add or remove a watch, and waits for the result.
+func (w *Watcher) request(c chan string, fname string) error {
+	select {
+	case c <- fname:
+	case <-w.done:
+		return ErrWatcherClosed
+	}
+	select {
+	case err := <-w.err:
+		return err
+	case <-w.done:
+		return ErrWatcherClosed
+	}
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"./edgo"
+)
+
+// Timeline replays journals through an EventHandler and returns the
+// color changes a TimelineDriver records.
+func Timeline(paths []string) ([]byte, error) {
+	var buf bytes.Buffer
+	h := &EventHandler{Driver: &TimelineDriver{W: &buf}}
+	events := make(chan interface{})
+	done := make(chan struct{})
+	go func() {
+		h.Run(context.Background(), events)
+		close(done)
+	}()
+
+	bulk := &edgo.BulkReader{Files: paths}
+	var commander string
+	err := bulk.Run(func(e *edgo.BulkEvent) error {
+		// Tag events with their commander, as the watcher does, so
+		// that commander rules are replayed too.
+		if name, ok := edgo.CommanderName(e.Event); ok {
+			commander = name
+		}
+		events <- &edgo.SourcedEvent{Source: e.File, Commander: commander, Event: e.Event}
+		return nil
+	})
+	close(events)
+	<-done
+	return buf.Bytes(), err
+}
+
+// RunGolden replays journals and compares the timeline with the golden
+// file, printing the differences. With update, or if the golden file
+// does not exist yet, the golden file is written instead.
+func RunGolden(golden string, paths []string, update bool) error {
+	timeline, err := Timeline(paths)
+	if err != nil {
+		return err
+	}
+	want, err := ioutil.ReadFile(golden)
+	if update || os.IsNotExist(err) {
+		fmt.Printf("golden: writing %s\n", golden)
+		return ioutil.WriteFile(golden, timeline, 0644)
+	}
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(want, timeline) {
+		fmt.Printf("golden: %s ok\n", golden)
+		return nil
+	}
+	fmt.Printf("--- %s\n+++ replay\n", golden)
+	for _, line := range diffLines(splitLines(want), splitLines(timeline)) {
+		fmt.Println(line)
+	}
+	return fmt.Errorf("golden: timeline differs from %s; rerun with -update to accept it", golden)
+}
+
+func splitLines(b []byte) []string {
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffLines returns the lines removed from a, prefixed with "-", and
+// added in b, prefixed with "+", using a longest common subsequence.
+// Unchanged lines are left out.
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "-"+a[i])
+			i++
+		default:
+			diff = append(diff, "+"+b[j])
+			j++
+		}
+	}
+	return diff
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// LEDDriver sets LEDs on a device.
+type LEDDriver interface {
+	SetColor(c Command) error
+}
+
+// TimedLEDDriver is an LEDDriver that records when each color is set,
+// by the timestamp of the event that set it.
+type TimedLEDDriver interface {
+	LEDDriver
+	SetColorAt(timestamp string, c Command) error
+}
+
+// VPCDriver sets LEDs with VPC_LED_Control.exe.
+type VPCDriver struct{}
+
+func (VPCDriver) SetColor(c Command) error {
+	return exec.Command(c.cmd, c.args...).Run()
+}
+
+// The arguments of a VPC_LED_Control.exe command are the vendor and
+// product ids of the device, the LED number and the red, green and
+// blue levels.
+
+// Device returns the vendor and product id, e.g. "3344:80CB".
+func (c Command) Device() string {
+	if len(c.args) < 2 {
+		return ""
+	}
+	return c.args[0] + ":" + c.args[1]
+}
+
+// LED returns the LED number.
+func (c Command) LED() string {
+	if len(c.args) < 3 {
+		return ""
+	}
+	return c.args[2]
+}
+
+// Color returns the color as hex RGB, e.g. "ff4040".
+func (c Command) Color() string {
+	if len(c.args) < 6 {
+		return ""
+	}
+	return strings.Join(c.args[3:6], "")
+}
+
+// Equal reports whether c and d run the same command.
+func (c Command) Equal(d Command) bool {
+	if c.cmd != d.cmd || len(c.args) != len(d.args) {
+		return false
+	}
+	for i := range c.args {
+		if c.args[i] != d.args[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Dim returns the command for c's color at a quarter of its brightness.
+func Dim(c Command) Command {
+	if len(c.args) < 6 {
+		return c
+	}
+	args := append([]string(nil), c.args...)
+	for i := 3; i < 6; i++ {
+		if v, err := strconv.ParseUint(args[i], 16, 8); err == nil {
+			args[i] = fmt.Sprintf("%02x", v/4)
+		}
+	}
+	return Command{c.cmd, args}
+}
+
+// TimelineDriver writes each color change to a timeline instead of a
+// device, as a line of timestamp, device, LED and color.
+type TimelineDriver struct {
+	W    io.Writer
+	Time string // timestamp of the event being handled; see SetColorAt
+}
+
+func (d *TimelineDriver) SetColorAt(timestamp string, c Command) error {
+	d.Time = timestamp
+	return d.SetColor(c)
+}
+
+func (d *TimelineDriver) SetColor(c Command) error {
+	_, err := fmt.Fprintf(d.W, "%s %s %s %s\n", d.Time, c.Device(), c.LED(), c.Color())
+	return err
+}
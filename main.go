@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"./edgo"
@@ -28,14 +33,18 @@ func (i *filterFlag) Set(value string) error {
 
 var (
 	ErrInterrupted = errors.New("main: interrupted")
+	ErrColor       = errors.New("main: colors are six hex digits, e.g. ff8000")
 	filters        filterFlag
+	excludes       filterFlag
+	statusFiles    filterFlag
+	colorRules     filterFlag
 )
 
 func waitForInterrupt(shutdown watch.Shutdown) {
-	sigc := make(chan os.Signal)
+	sigc := make(chan os.Signal, 1)
 	defer close(sigc)
 
-	signal.Notify(sigc, os.Interrupt)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(sigc)
 
 	select {
@@ -54,102 +63,481 @@ type Command struct {
 	args []string
 }
 
+const VPCLEDControl = "C:\\Program Files (x86)\\VPC Software Suite\\tools\\VPC_LED_Control.exe"
+
 var (
 	ColorIndex = []string{"00", "40", "80", "ff"}
 
-	White := Command{"C:\\Program Files (x86)\\VPC Software Suite\\tools\\VPC_LED_Control.exe", []string{"3344", "80CB", "01", ColorIndex[3], ColorIndex[3], ColorIndex[3]}},
+	// Idle is set on exit, on the journal Shutdown event and after
+	// IdleTimeout without writes from the game; see the -idle flag.
+	Idle = Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[0], ColorIndex[0], ColorIndex[0]}}
+
+	// Times without writes from the game after which the LEDs are
+	// dimmed, show the screensaver, and go idle; 0 disables each.
+	DimAfter         = 5 * time.Minute
+	ScreensaverAfter = 15 * time.Minute
+	IdleTimeout      = 30 * time.Minute
+
+	// Screensaver holds the colors the screensaver cycles through, one
+	// every ScreensaverStep.
+	Screensaver = []Command{
+		Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[1], ColorIndex[0], ColorIndex[2]}},
+		Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[0], ColorIndex[1], ColorIndex[2]}},
+		Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[0], ColorIndex[2], ColorIndex[1]}},
+		Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[1], ColorIndex[1], ColorIndex[1]}},
+	}
+	ScreensaverStep = 2 * time.Second
+
+	White = Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[3], ColorIndex[3], ColorIndex[3]}}
 
 	CmdMapping = map[string]Command{
-		"Docked":      White,
-		"Docked":      White,
-		"Docked":      White,
-		"FSDJump":      Command{"C:\\Program Files (x86)\\VPC Software Suite\\tools\\VPC_LED_Control.exe", []string{"3344", "80CB", "01", ColorIndex[1], ColorIndex[3], ColorIndex[1]}},
-		"FuelScoop":    Command{"C:\\Program Files (x86)\\VPC Software Suite\\tools\\VPC_LED_Control.exe", []string{"3344", "80CB", "01", ColorIndex[2], ColorIndex[2], ColorIndex[0]}},
-		"HeatDamage":   Command{"C:\\Program Files (x86)\\VPC Software Suite\\tools\\VPC_LED_Control.exe", []string{"3344", "80CB", "01", ColorIndex[3], ColorIndex[1], ColorIndex[0]}},
-		"HeatWarning":  Command{"C:\\Program Files (x86)\\VPC Software Suite\\tools\\VPC_LED_Control.exe", []string{"3344", "80CB", "01", ColorIndex[2], ColorIndex[1], ColorIndex[1]}},
-		"HullDamage":   Command{"C:\\Program Files (x86)\\VPC Software Suite\\tools\\VPC_LED_Control.exe", []string{"3344", "80CB", "01", ColorIndex[3], ColorIndex[0], ColorIndex[0]}},
-		"Interdicted":  Command{"C:\\Program Files (x86)\\VPC Software Suite\\tools\\VPC_LED_Control.exe", []string{"3344", "80CB", "01", ColorIndex[3], ColorIndex[1], ColorIndex[1]}},
-		"Interdiction": Command{"C:\\Program Files (x86)\\VPC Software Suite\\tools\\VPC_LED_Control.exe", []string{"3344", "80CB", "01", ColorIndex[2], ColorIndex[1], ColorIndex[1]}},
-		"UnderAttack":  Command{"C:\\Program Files (x86)\\VPC Software Suite\\tools\\VPC_LED_Control.exe", []string{"3344", "80CB", "01", ColorIndex[3], ColorIndex[1], ColorIndex[1]}},
+		"Docked":       White,
+		"FSDJump":      Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[1], ColorIndex[3], ColorIndex[1]}},
+		"FuelScoop":    Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[2], ColorIndex[2], ColorIndex[0]}},
+		"HeatDamage":   Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[3], ColorIndex[1], ColorIndex[0]}},
+		"HeatWarning":  Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[2], ColorIndex[1], ColorIndex[1]}},
+		"HullDamage":   Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[3], ColorIndex[0], ColorIndex[0]}},
+		"Interdicted":  Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[3], ColorIndex[1], ColorIndex[1]}},
+		"Interdiction": Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[2], ColorIndex[1], ColorIndex[1]}},
+		"Shutdown":     Idle,
+		"UnderAttack":  Command{VPCLEDControl, []string{"3344", "80CB", "01", ColorIndex[3], ColorIndex[1], ColorIndex[1]}},
 	}
 )
 
-// .\VPC_LED_Control.exe 3344 80CB 01 00 ff 00
-func ChangeVPColor(events chan Command, shutdown watch.Shutdown) {
-	for {
-		select {
-		case e := <-events:
-			cmd := exec.Command(e.cmd, e.args...)
-			if err := cmd.Run(); err != nil {
-				log.Println("Error: ", err)
-			}
+// HandlerEvents are the events EventHandler relies on to go idle, which
+// are added to the -f filters.
+var HandlerEvents = []string{"GameIdle", "GameResumed", "Shutdown", "SessionStart", "SessionContinued", "SessionEnd"}
 
-		case <-shutdown.Dying():
-			return
+// timedCommand is a command with the timestamp of the event that
+// caused it.
+type timedCommand struct {
+	Command
+	time string
+}
+
+// ChangeColor sends each command to the driver, until commands is
+// closed.
+func ChangeColor(driver LEDDriver, commands <-chan timedCommand) {
+	for c := range commands {
+		var err error
+		if td, ok := driver.(TimedLEDDriver); ok {
+			err = td.SetColorAt(c.time, c.Command)
+		} else {
+			err = driver.SetColor(c.Command)
+		}
+		if err != nil {
+			log.Println("Error: ", err)
 		}
 	}
 }
 
-func HandleEvents(events chan interface{}, shutdown watch.Shutdown) {
-	startTime := time.Now()
-	cmd := make(chan Command)
-	go ChangeVPColor(cmd, shutdown)
+// ColorCommand returns the command that sets the LED to a hex RGB
+// color such as "ff8000".
+func ColorCommand(color string) (Command, error) {
+	if len(color) != 6 {
+		return Command{}, ErrColor
+	}
+	if _, err := strconv.ParseUint(color, 16, 32); err != nil {
+		return Command{}, ErrColor
+	}
+	color = strings.ToLower(color)
+	return Command{VPCLEDControl, []string{"3344", "80CB", "01", color[0:2], color[2:4], color[4:6]}}, nil
+}
+
+// Idle actions.
+const (
+	IdleDim         = "dim"
+	IdleScreensaver = "screensaver"
+	IdleOff         = "off"
+)
+
+// IdleRule is what an EventHandler does once the game has written nothing
+// for After.
+type IdleRule struct {
+	After  time.Duration
+	Action string
+}
+
+// IdleRules returns the enabled idle rules, sorted by time. The watcher
+// is given their times as its IdleThresholds, so a GameIdle event of
+// level n is for the nth rule.
+func IdleRules() []IdleRule {
+	var rules []IdleRule
+	for _, r := range []IdleRule{
+		{DimAfter, IdleDim},
+		{ScreensaverAfter, IdleScreensaver},
+		{IdleTimeout, IdleOff},
+	} {
+		if r.After > 0 {
+			rules = append(rules, r)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].After < rules[j].After })
+	return rules
+}
+
+// EventCommand returns the command CmdMapping maps an event to. A rule
+// keyed "Commander/Event" applies to that commander's events only, and
+// takes precedence over the rule for any commander, keyed "Event".
+func EventCommand(e interface{}) (Command, bool) {
+	name := edgo.GetEventName(e)
+	if s, ok := e.(*edgo.SourcedEvent); ok && s.Commander != "" {
+		if c, ok := CmdMapping[s.Commander+"/"+name]; ok {
+			return c, true
+		}
+	}
+	c, ok := CmdMapping[name]
+	return c, ok
+}
+
+// AddColorRule adds a rule to CmdMapping from a "[Commander/]Event=color"
+// string, e.g. "Jameson/FSDJump=00ff00".
+func AddColorRule(rule string) error {
+	i := strings.LastIndex(rule, "=")
+	if i <= 0 {
+		return fmt.Errorf("main: color rule %q is not [Commander/]Event=color", rule)
+	}
+	c, err := ColorCommand(rule[i+1:])
+	if err != nil {
+		return err
+	}
+	CmdMapping[rule[:i]] = c
+	return nil
+}
+
+// minLevel returns the lowest idle level of all sources.
+func minLevel(levels map[string]int) int {
+	first := true
+	min := 0
+	for _, l := range levels {
+		if first || l < min {
+			min, first = l, false
+		}
+	}
+	return min
+}
+
+// EventHandler maps events to LED commands.
+type EventHandler struct {
+	Driver LEDDriver
+	State  *edgo.GameState // if set, the LEDs start in the colors of the saved state
+	Since  time.Time       // events timestamped before the second of Since are only logged; zero handles all
+
+	// Sources names the sources of the merged events. The LEDs only
+	// go idle once all of them are; sources not named here count from
+	// their first event.
+	Sources []string
+}
+
+// Run maps events to LED commands until events is closed or ctx is
+// done. It returns once the last command, which sets the LEDs to Idle,
+// has been sent to the driver. A command is only sent when it changes
+// the color.
+func (h *EventHandler) Run(ctx context.Context, events <-chan interface{}) {
+	cmd := make(chan timedCommand)
+	done := make(chan struct{})
+	go func() {
+		ChangeColor(h.Driver, cmd)
+		close(done)
+	}()
+
+	// at is the timestamp of the latest event, which commands are
+	// sent with.
+	at := edgo.JournalTime(time.Now())
+	// sent is the command the driver was given last; sending it again
+	// would not change the LEDs.
+	var sent *Command
+	send := func(c Command) {
+		if sent != nil && sent.Equal(c) {
+			return
+		}
+		sent = &c
+		cmd <- timedCommand{c, at}
+	}
+	defer func() {
+		send(Idle)
+		close(cmd)
+		<-done
+	}()
+
+	// last is the color set by the latest event, which GameResumed
+	// restores.
+	last := Idle
+	set := func(c Command) {
+		last = c
+		send(c)
+	}
+
+	var screensaver *time.Ticker
+	var tick <-chan time.Time
+	var step int
+	stopScreensaver := func() {
+		if screensaver != nil {
+			screensaver.Stop()
+			screensaver, tick = nil, nil
+		}
+	}
+	defer stopScreensaver()
+
+	rules := IdleRules()
+	gameShutdown := false
+
+	// Journal timestamps are in whole seconds, so an event written in
+	// the second the handler started is not older than it.
+	since := h.Since.Truncate(time.Second)
+
+	// levels holds the idle level of each source. The LEDs follow the
+	// least idle one, so that a commander still playing keeps them lit.
+	levels := make(map[string]int)
+	for _, name := range h.Sources {
+		levels[name] = 0
+	}
+	level := 0
+
+	if h.State != nil && h.State.Snapshot().Docked {
+		// Restore the colors for a saved game state.
+		set(CmdMapping["Docked"])
+	}
 
 	for {
 		select {
-		case e := <-events:
-			t := edgo.GetEventTimestamp(e)
-			if t2, err := time.Parse(time.RFC3339, t); err == nil || t2.After(startTime) {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			source, event := "", e
+			if s, ok := e.(*edgo.SourcedEvent); ok {
+				source, event = s.Source, s.Event
+			}
+			if t := edgo.GetEventTimestamp(e); t != "" {
+				at = t
+			}
+			if idle, ok := event.(*edgo.IdleEvent); ok {
+				log.Println(source, idle.Event, ":", idle.Level, idle.Seconds)
+				levels[source] = idle.Level
+				if n := minLevel(levels); n != level {
+					level = n
+				} else {
+					continue
+				}
+				if level == 0 {
+					stopScreensaver()
+					send(last)
+					continue
+				}
+				if gameShutdown || level > len(rules) {
+					continue
+				}
+				switch rules[level-1].Action {
+				case IdleDim:
+					send(Dim(last))
+				case IdleScreensaver:
+					if screensaver == nil && len(Screensaver) > 0 {
+						screensaver = time.NewTicker(ScreensaverStep)
+						tick = screensaver.C
+						step = 0
+					}
+				case IdleOff:
+					stopScreensaver()
+					send(Idle)
+				}
+				continue
+			}
+
+			stopScreensaver()
+			if t, err := time.Parse(time.RFC3339, at); err == nil && !t.Before(since) {
 				name := edgo.GetEventName(e)
-				log.Println(name, ":", e)
-				if v, ok := CmdMapping[name]; ok {
-					cmd <- v
+				log.Println(source, name, ":", event)
+				if v, ok := EventCommand(e); ok {
+					set(v)
+					gameShutdown = name == "Shutdown"
 				}
 			}
 
-		case <-shutdown.Dying():
-			close(cmd)
+		case now := <-tick:
+			at = edgo.JournalTime(now)
+			send(Screensaver[step%len(Screensaver)])
+			step++
+
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
 func main() {
-	flag.Var(&filters, "f", "Filtered events.")
+	flag.Var(&filters, "f", "Include events matching `pattern`, e.g. 'Fsd*' or 'ReceiveText:Channel=player'.")
+	flag.Var(&excludes, "x", "Exclude events matching `pattern`.")
+	flag.Var(&statusFiles, "s", "Only read the named status `file`, e.g. Status or Cargo.")
+	flag.Var(&colorRules, "c", "Set the color for an event, for one commander or any: `[Commander/]Event=color`, e.g. Jameson/FSDJump=00ff00.")
+	relay := flag.String("relay", "", "Take events from the relay server at `address` instead of watching journals; see edgo relay serve.")
+	relayToken := flag.String("relay-token", os.Getenv("EDGO_RELAY_TOKEN"), "Send `token` to the relay server; defaults to $EDGO_RELAY_TOKEN.")
+	stateFile := flag.String("state", "", "Save and restore the game state to `file`.")
+	golden := flag.String("golden", "", "Replay the journals given as arguments and compare the LED timeline with golden `file`.")
+	update := flag.Bool("update", false, "With -golden, rewrite the golden file instead of comparing.")
+	idleColor := flag.String("idle", "000000", "Idle LED `color` as hex RGB, set on exit, on game shutdown and after -idle-timeout.")
+	flag.DurationVar(&DimAfter, "dim", DimAfter, "Dim the LEDs after the game writes nothing for `duration`; 0 never does.")
+	flag.DurationVar(&ScreensaverAfter, "screensaver", ScreensaverAfter, "Start the screensaver after the game writes nothing for `duration`; 0 never does.")
+	flag.DurationVar(&IdleTimeout, "idle-timeout", IdleTimeout, "Go idle after the game writes nothing for `duration`; 0 never does.")
 	flag.Parse()
 
-	var directory string
-	if flag.NFlag() > 1 {
-		directory = flag.Arg(1)
-	} else if homedir, err := os.UserHomeDir(); err == nil {
-		directory = filepath.Join(homedir, "Saved Games", "Frontier Developments", "Elite Dangerous")
-	}
-	if directory == "" {
-		fmt.Printf("Usage: %s <path to elite dangerous journal>\n", filepath.Base(os.Args[0]))
+	idle, err := ColorCommand(*idleColor)
+	if err != nil {
+		fmt.Println(err)
 		os.Exit(1)
 	}
+	Idle = idle
+	CmdMapping["Shutdown"] = Idle
+	for _, rule := range colorRules {
+		if err := AddColorRule(rule); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
 
-	fmt.Printf("Using: %s %s\n", filepath.Base(os.Args[0]), directory)
+	if *golden != "" {
+		if err := RunGolden(*golden, flag.Args(), *update); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	shutdown := watch.NewShutdown()
-	w := edgo.NewEliteWatcher(directory, shutdown)
-	defer w.Close()
+	// Each argument is a journal directory, optionally named for its
+	// commander or account as name=directory.
+	type source struct{ name, dir string }
+	var sources []source
+	for _, arg := range flag.Args() {
+		name, dir := arg, arg
+		if i := strings.Index(arg, "="); i > 0 {
+			name, dir = arg[:i], arg[i+1:]
+		}
+		sources = append(sources, source{name, dir})
+	}
+	if len(sources) == 0 && *relay == "" {
+		// Pick the candidate with the most recent journal.
+		dirs := edgo.FindJournalDirs()
+		for _, d := range dirs {
+			log.Printf("main: found %s journals in %s (latest %s)", d.Source, d.Path, d.LatestJournal)
+		}
+		if directory := edgo.PickJournalDir(dirs); directory != "" {
+			sources = append(sources, source{directory, directory})
+		}
+	}
+	if len(sources) == 0 && *relay == "" {
+		fmt.Printf("Usage: %s [[name=]<path to elite dangerous journal>...]\n", filepath.Base(os.Args[0]))
+		os.Exit(1)
+	}
 
-	if len(filters) > 0 {
+	var filter *edgo.Filter
+	if len(filters) > 0 || len(excludes) > 0 {
 		// Only add event filters if they have been specified on the comand line.
-		w.EventFilter = make(map[string]struct{})
+		if len(filters) > 0 {
+			filters = append(filters, HandlerEvents...)
+		}
+		var err error
+		if filter, err = edgo.CompileFilter(filters, excludes); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 		for _, v := range filters {
-			w.EventFilter[v] = struct{}{}
 			log.Println("main: filter ", v)
 		}
-		w.EventFilter["NavRoute"] = struct{}{}
-		log.Println("main: filter NavRoute")
+		for _, v := range excludes {
+			log.Println("main: exclude ", v)
+		}
+	}
+	if len(statusFiles) > 0 {
+		log.Println("main: status files ", statusFiles)
 	}
 
-	go w.Main()
-	go HandleEvents(w.Journals, shutdown)
+	shutdown := watch.NewShutdown()
+	ctx, cancel := watch.WithShutdown(context.Background(), shutdown)
+	defer cancel()
+
+	// Events come from a relay, or from a watcher for each directory.
+	var events <-chan interface{}
+	var runs []func(context.Context) error
+	var watchers []*edgo.EliteWatcher
+	var names []string
+	if *relay != "" {
+		fmt.Printf("Using: %s -relay %s\n", filepath.Base(os.Args[0]), *relay)
+		client := edgo.NewRelayClient(*relay)
+		client.Token = *relayToken
+		events = client.Journals
+		runs = append(runs, client.Run)
+		sources = nil
+	}
+	for _, src := range sources {
+		fmt.Printf("Using: %s %s\n", filepath.Base(os.Args[0]), src.dir)
+		w := edgo.NewEliteWatcher(src.dir, shutdown)
+		w.Source = src.name
+		names = append(names, src.name)
+		for _, r := range IdleRules() {
+			w.IdleThresholds = append(w.IdleThresholds, r.After)
+		}
+		w.EventFilter = filter
+		if len(statusFiles) > 0 {
+			if err := w.WatchStatusFiles(statusFiles...); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+		watchers = append(watchers, w)
+		runs = append(runs, w.Run)
+	}
+	if len(watchers) > 0 {
+		events = edgo.MergeJournals(ctx, watchers...)
+	}
+
+	var state *edgo.GameState
+	if *stateFile != "" {
+		state = edgo.NewGameState()
+		if err := state.Load(*stateFile); err != nil && !os.IsNotExist(err) {
+			log.Println("main: state ", err)
+		}
+		// The state is of a single game, so only the first
+		// directory updates it.
+		if len(watchers) > 0 {
+			watchers[0].State = state
+		}
+	}
+
+	// Each watcher, or the relay client, closes its Journals when it
+	// stops, and the handler returns once it has handled the last event
+	// of them all. AutoSave is waited for too, so that it is not still
+	// writing the state file when it is saved a final time.
+	var wg sync.WaitGroup
+	if state != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			state.AutoSave(ctx, *stateFile, time.Minute)
+		}()
+	}
+	for _, run := range runs {
+		wg.Add(1)
+		go func(run func(context.Context) error) {
+			defer wg.Done()
+			if err := run(ctx); err != nil {
+				shutdown.Kill(err)
+			}
+		}(run)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h := &EventHandler{Driver: VPCDriver{}, State: state, Since: time.Now(), Sources: names}
+		h.Run(ctx, events)
+	}()
 
 	waitForInterrupt(shutdown)
+	wg.Wait()
+	if state != nil {
+		if err := state.Save(*stateFile); err != nil {
+			log.Println("main: state ", err)
+		}
+	}
 	log.Println("done...")
 }
 
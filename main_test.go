@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"./edgo"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+func TestGoldenTimeline(t *testing.T) {
+	const golden = "testdata/default.golden"
+	got, err := Timeline([]string{"testdata/journals"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		diff := diffLines(splitLines(want), splitLines(got))
+		t.Errorf("timeline differs from %s; rerun with -update to accept it:\n%s", golden, strings.Join(diff, "\n"))
+	}
+}
+
+// handle runs events through an EventHandler for sources and returns
+// the timeline.
+func handle(sources []string, events ...interface{}) string {
+	var buf bytes.Buffer
+	h := &EventHandler{Driver: &TimelineDriver{W: &buf}, Sources: sources}
+	c := make(chan interface{}, len(events))
+	for _, e := range events {
+		c <- e
+	}
+	close(c)
+	h.Run(context.Background(), c)
+	return buf.String()
+}
+
+func idleEvent(source string, level int) interface{} {
+	e := &edgo.IdleEvent{Level: level}
+	e.Timestamp = "2025-01-01T12:10:00Z"
+	e.Event = "GameIdle"
+	if level == 0 {
+		e.Event = "GameResumed"
+	}
+	return &edgo.SourcedEvent{Source: source, Event: e}
+}
+
+func TestIdleFollowsMostActiveSource(t *testing.T) {
+	jump := &edgo.SourcedEvent{Source: "a", Event: edgo.Json{
+		"timestamp": "2025-01-01T12:00:00Z",
+		"event":     "FSDJump",
+	}}
+	fsd := CmdMapping["FSDJump"].Color()
+	dim := Dim(CmdMapping["FSDJump"]).Color()
+	off := Idle.Color()
+
+	tests := []struct {
+		name    string
+		sources []string
+		events  []interface{}
+		want    []string // colors set
+	}{
+		{"one source idle", []string{"a"},
+			[]interface{}{jump, idleEvent("a", 1)},
+			[]string{fsd, dim, off}},
+		{"other source never idle", []string{"a", "b"},
+			[]interface{}{jump, idleEvent("a", 1)},
+			[]string{fsd, off}},
+		{"both sources idle", []string{"a", "b"},
+			[]interface{}{jump, idleEvent("a", 1), idleEvent("b", 1)},
+			[]string{fsd, dim, off}},
+		{"resumed", []string{"a", "b"},
+			[]interface{}{jump, idleEvent("a", 1), idleEvent("b", 1), idleEvent("b", 0)},
+			[]string{fsd, dim, fsd, off}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, line := range splitLines([]byte(handle(tt.sources, tt.events...))) {
+			f := strings.Fields(line)
+			got = append(got, f[len(f)-1])
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s: colors %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandlerSendsEachColorOnce(t *testing.T) {
+	event := func(name string) interface{} {
+		return edgo.Json{"timestamp": "2025-01-01T12:00:00Z", "event": name}
+	}
+	fsd := CmdMapping["FSDJump"].Color()
+	off := Idle.Color()
+
+	tests := []struct {
+		name   string
+		events []interface{}
+		want   []string // colors set
+	}{
+		{"repeated event", []interface{}{event("FSDJump"), event("FSDJump")},
+			[]string{fsd, off}},
+		{"shutdown", []interface{}{event("FSDJump"), event("Shutdown")},
+			[]string{fsd, off}},
+		{"shutdown then idle", []interface{}{event("FSDJump"), event("Shutdown"), idleEvent("", 1)},
+			[]string{fsd, off}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, line := range splitLines([]byte(handle(nil, tt.events...))) {
+			f := strings.Fields(line)
+			got = append(got, f[len(f)-1])
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s: colors %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterKeepsHandlerEvents(t *testing.T) {
+	filter, err := edgo.CompileFilter(append([]string{"FSDJump"}, HandlerEvents...), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"FSDJump", "Shutdown", "GameIdle", "GameResumed", "SessionStart", "SessionEnd"} {
+		if match, _ := filter.MatchName(name); !match {
+			t.Errorf("%s is filtered out", name)
+		}
+	}
+	if match, _ := filter.MatchName("Docked"); match {
+		t.Error("Docked is not filtered out")
+	}
+}
+
+func TestHandlerSince(t *testing.T) {
+	event := func(name, timestamp string) interface{} {
+		return edgo.Json{"timestamp": timestamp, "event": name}
+	}
+	var buf bytes.Buffer
+	h := &EventHandler{
+		Driver: &TimelineDriver{W: &buf},
+		// Started part way through a second.
+		Since: time.Date(2025, 1, 1, 12, 0, 0, 700e6, time.UTC),
+	}
+	c := make(chan interface{}, 3)
+	c <- event("FSDJump", "2025-01-01T11:59:59Z")
+	c <- event("Docked", "2025-01-01T12:00:00Z")
+	c <- event("HullDamage", "2025-01-01T12:00:01Z")
+	close(c)
+	h.Run(context.Background(), c)
+
+	var got []string
+	for _, line := range splitLines(buf.Bytes()) {
+		got = append(got, strings.Fields(line)[0])
+	}
+	want := []string{"2025-01-01T12:00:00Z", "2025-01-01T12:00:01Z", "2025-01-01T12:00:01Z"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("commands at %v, want %v", got, want)
+	}
+}